@@ -0,0 +1,134 @@
+package envdoc
+
+import "fmt"
+
+// Assertion is a named expression over several variables that must hold.
+// Message is reported when it does not; neither ever includes values. See
+// expr.go for the expression language.
+type Assertion struct {
+	Name     string   `yaml:"name"`
+	Expr     string   `yaml:"expr"`
+	Message  string   `yaml:"message,omitempty"`
+	Severity Severity `yaml:"severity,omitempty"`
+
+	// expr is Expr compiled by validateAssertions; nil for assertions that
+	// were never validated.
+	expr *compiledExpr
+}
+
+// AssertionResult holds the result of a single Assertion. Skipped is set
+// when the expression reads a variable that is unset or does not parse.
+type AssertionResult struct {
+	Name     string    `json:"name"`
+	Valid    bool      `json:"valid"`
+	Skipped  bool      `json:"skipped,omitempty"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// inspectAssertion evaluates a against env.
+func inspectAssertion(env EnvReader, a Assertion, rules []Rule) AssertionResult {
+	ar := AssertionResult{Name: a.Name, Valid: true}
+	sev := a.Severity
+	if sev == "" {
+		sev = SeverityError
+	}
+	e, err := a.expr, error(nil)
+	if e == nil {
+		e, err = compileExpr(a.Expr, rules)
+	}
+	if err != nil {
+		ar.Valid = false
+		ar.Problems = append(ar.Problems, Problem{
+			Code: CodeExprInvalid, Field: "expr", Severity: SeverityError, Expected: a.Expr,
+			Message: "invalid expr: " + err.Error(),
+		})
+		return ar
+	}
+	ok, err := e.eval(env)
+	if err != nil {
+		ar.Skipped = true
+		return ar
+	}
+	if !ok {
+		msg := a.Message
+		if msg == "" {
+			msg = "assertion failed"
+		}
+		ar.Valid = sev != SeverityError
+		ar.Problems = append(ar.Problems, Problem{
+			Code: CodeAssertionFailed, Field: "expr", Severity: sev, Expected: a.Expr, Message: msg,
+		})
+	}
+	return ar
+}
+
+// checkExpr records a problem when a set variable's rule expression does
+// not hold. Undecidable expressions are skipped.
+func checkExpr(env EnvReader, vr *VarResult, rule Rule, rules []Rule) {
+	if rule.Expr == "" || !vr.Present {
+		return
+	}
+	e, err := rule.expr, error(nil)
+	if e == nil {
+		e, err = compileExpr(rule.Expr, rules)
+	}
+	if err != nil {
+		vr.addProblem(rule, newProblem(CodeExprInvalid, "expr", rule.Expr, "invalid expr: %s", err))
+		return
+	}
+	if ok, err := e.eval(env); err != nil || ok {
+		return
+	}
+	msg := rule.ExprMessage
+	if msg == "" {
+		msg = "expr not satisfied"
+	}
+	vr.addProblem(rule, newProblem(CodeExprFailed, "expr", rule.Expr, "%s", msg))
+}
+
+// validateExprs compiles and type-checks every rule expression, keeping the
+// result in the rule.
+func validateExprs(rules []Rule) error {
+	for idx, r := range rules {
+		if r.Expr == "" {
+			if r.ExprMessage != "" {
+				return fmt.Errorf("envdoc: rule[%d] (%s): expr_message requires expr", idx, r.name())
+			}
+			continue
+		}
+		e, err := compileExpr(r.Expr, rules)
+		if err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): expr: %w", idx, r.name(), err)
+		}
+		rules[idx].expr = e
+	}
+	return nil
+}
+
+// validateAssertions checks that assertions have unique names, a valid
+// severity, and expressions that compile against rules, keeping the compiled
+// expressions in the assertions.
+func validateAssertions(assertions []Assertion, rules []Rule) error {
+	seen := make(map[string]bool)
+	for idx, a := range assertions {
+		if a.Name == "" {
+			return fmt.Errorf("envdoc: assertion[%d]: name is required", idx)
+		}
+		if seen[a.Name] {
+			return fmt.Errorf("envdoc: assertion[%d]: duplicate name %q", idx, a.Name)
+		}
+		seen[a.Name] = true
+		if a.Expr == "" {
+			return fmt.Errorf("envdoc: assertion[%d] (%s): expr is required", idx, a.Name)
+		}
+		if _, ok := severityRank[a.Severity]; a.Severity != "" && !ok {
+			return fmt.Errorf("envdoc: assertion[%d] (%s): unknown severity %q (want error, warning or info)", idx, a.Name, a.Severity)
+		}
+		e, err := compileExpr(a.Expr, rules)
+		if err != nil {
+			return fmt.Errorf("envdoc: assertion[%d] (%s): expr: %w", idx, a.Name, err)
+		}
+		assertions[idx].expr = e
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package envdoc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const assertionYAML = `
+rules:
+  - key: REPLICAS
+    type: int
+  - key: CACHE_BACKEND
+    allowed: [memory, redis]
+  - key: WORKERS
+    type: int
+    expr: "WORKERS <= REPLICAS"
+    expr_message: "must not exceed REPLICAS"
+assertions:
+  - name: shared-cache
+    expr: "if REPLICAS > 1 then CACHE_BACKEND != 'memory'"
+    message: "multiple replicas need a shared cache"
+  - name: tls-pair
+    expr: "set(TLS_CERT) == set(TLS_KEY)"
+    severity: warning
+`
+
+func TestAssertions(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(assertionYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := MapEnvReader{"REPLICAS": "3", "CACHE_BACKEND": "memory", "WORKERS": "4", "TLS_CERT": "/tls/cert.pem"}
+	report := inspect(env, fixedClock{t: time.Now()}, *rs, Config{}, nil)
+
+	want := Problem{Code: CodeExprFailed, Field: "expr", Severity: SeverityError, Expected: "WORKERS <= REPLICAS", Message: "must not exceed REPLICAS"}
+	if r := report.Results[2]; r.Valid || len(r.Problems) != 1 || r.Problems[0] != want {
+		t.Errorf("expected %+v, got %+v", want, r.Problems)
+	}
+	if len(report.Assertions) != 2 {
+		t.Fatalf("expected 2 assertion results, got %d", len(report.Assertions))
+	}
+	shared := report.Assertions[0]
+	if shared.Valid || shared.Problems[0].Code != CodeAssertionFailed || shared.Problems[0].Message != "multiple replicas need a shared cache" {
+		t.Errorf("expected shared-cache failure, got %+v", shared)
+	}
+	tls := report.Assertions[1]
+	if !tls.Valid || tls.Problems[0].Severity != SeverityWarning || tls.Problems[0].Message != "assertion failed" {
+		t.Errorf("expected tls-pair warning, got %+v", tls)
+	}
+
+	var buf bytes.Buffer
+	LogReport(&buf, report)
+	out := buf.String()
+	for _, want := range []string{
+		`envdoc: assertion=shared-cache valid=false severity=error problem="error ENV_ASSERTION_FAILED: multiple replicas need a shared cache"`,
+		`envdoc: assertion=tls-pair valid=true severity=warning`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "/tls/cert.pem") || strings.Contains(out, "=memory") {
+		t.Errorf("log leaked a value:\n%s", out)
+	}
+
+	err = CheckFailFast(report, true)
+	if err == nil || !strings.Contains(err.Error(), "1 assertion(s) failed") {
+		t.Errorf("expected assertion fail-fast error, got %v", err)
+	}
+	if err := CheckFailOn(report, SeverityWarning); err == nil || !strings.Contains(err.Error(), "2 assertion(s) failed") {
+		t.Errorf("expected warning threshold to count both assertions, got %v", err)
+	}
+}
+
+func TestAssertions_Skipped(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(assertionYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := MapEnvReader{"REPLICAS": "lots", "WORKERS": "4"}
+	report := inspect(env, fixedClock{t: time.Now()}, *rs, Config{}, nil)
+
+	if a := report.Assertions[0]; !a.Valid || !a.Skipped || len(a.Problems) != 0 {
+		t.Errorf("expected skipped assertion, got %+v", a)
+	}
+	if r := report.Results[2]; len(r.Problems) != 0 {
+		t.Errorf("expected expr to be skipped when REPLICAS does not parse, got %+v", r.Problems)
+	}
+
+	var buf bytes.Buffer
+	LogReport(&buf, report)
+	if !strings.Contains(buf.String(), "envdoc: assertion=shared-cache valid=true skipped=true") {
+		t.Errorf("expected skipped assertion line, got:\n%s", buf.String())
+	}
+}
+
+func TestAssertions_CompiledOnLoad(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(assertionYAML + `
+profiles:
+  dev:
+    - key: REPLICAS
+      default: "1"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Rules[2].expr == nil {
+		t.Error("expected the rule expr to be compiled when loading")
+	}
+	for _, a := range rs.Assertions {
+		if a.expr == nil {
+			t.Errorf("expected assertion %s to be compiled when loading", a.Name)
+		}
+	}
+
+	loaded := rs.Assertions[0].expr
+	if err := rs.ApplyProfile("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if rs.Rules[2].expr == nil || rs.Assertions[0].expr == nil || rs.Assertions[0].expr == loaded {
+		t.Error("expected the profile to recompile expressions against its rules")
+	}
+}
+
+func TestLoadRuleSet_ProfileBreaksAssertion(t *testing.T) {
+	_, err := LoadRuleSet([]byte(`
+rules:
+  - key: REPLICAS
+    type: int
+assertions:
+  - name: scaled
+    expr: "REPLICAS > 1"
+profiles:
+  odd:
+    - key: REPLICAS
+      type: string
+`))
+	if err == nil || !strings.Contains(err.Error(), `profile "odd"`) || !strings.Contains(err.Error(), "scaled") {
+		t.Errorf("expected the profile to fail the assertion type check, got %v", err)
+	}
+}
+
+func TestValidateAssertions(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"no name", "assertions:\n  - expr: \"true\"\n", "assertion[0]: name is required"},
+		{"duplicate", "assertions:\n  - name: a\n    expr: \"true\"\n  - name: a\n    expr: \"true\"\n", `assertion[1]: duplicate name "a"`},
+		{"no expr", "assertions:\n  - name: a\n", "assertion[0] (a): expr is required"},
+		{"severity", "assertions:\n  - name: a\n    expr: \"true\"\n    severity: fatal\n", `unknown severity "fatal"`},
+		{"type error", "rules:\n  - key: N\n    type: int\nassertions:\n  - name: a\n    expr: \"N == 'x'\"\n", "assertion[0] (a): expr: cannot compare int with string"},
+		{"expr_message alone", "rules:\n  - key: N\n    expr_message: nope\n", "rule[0] (N): expr_message requires expr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRuleSet([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
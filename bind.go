@@ -0,0 +1,285 @@
+package envdoc
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Bind derives rules from dst, a pointer to a struct tagged as for
+// RulesFromStruct, then inspects the environment and decodes it into dst.
+// Options are applied after the derived rules, so WithRules or WithRuleSet
+// replaces them. See Inspector.Bind.
+func Bind(dst any, opts ...Option) (*Report, error) {
+	if err := checkBindTarget(dst); err != nil {
+		return nil, err
+	}
+	rules, err := RulesFromStruct(dst)
+	if err != nil {
+		return nil, err
+	}
+	return New(append([]Option{WithRules(rules)}, opts...)...).Bind(dst)
+}
+
+// Bind inspects the environment and logs the report like Run. If the report
+// passes the fail-fast check (whether or not fail-fast is enabled), each
+// env-tagged field of dst is set from its variable, or from its rule's
+// default when the variable is unset; fields with neither keep their value.
+// Values are parsed as inspection parses them. Since every invalid variable
+// fails the check, even an optional one such as PORT=5000 with max 100, no
+// field is ever set from an invalid value.
+//
+// A field that cannot hold a value its rule accepts (e.g. 300 for an int8
+// under a hand-written rule without max) is a binding failure. Failures are reported as ENV_BIND_FAILED problems on the variable
+// in the returned report and in later reports from the Inspector, so
+// /debug/env shows them, and Bind returns an error listing them.
+func (i *Inspector) Bind(dst any) (*Report, error) {
+	if err := checkBindTarget(dst); err != nil {
+		return nil, err
+	}
+	if i.err != nil {
+		return nil, i.err
+	}
+	// Inspect afresh: the failures of an earlier Bind are replaced below.
+	report := inspect(i.env, i.clock, i.ruleSet, i.config, i.types)
+	if err := CheckFailOn(report, i.config.failOn()); err != nil {
+		LogReport(i.output, report)
+		return report, err
+	}
+
+	failures := i.bind(reflect.ValueOf(dst).Elem())
+	i.mu.Lock()
+	i.bindFailures = failures
+	i.mu.Unlock()
+	addBindFailures(report, failures)
+	LogReport(i.output, report)
+
+	if len(failures) == 0 {
+		return report, nil
+	}
+	msgs := make([]string, len(failures))
+	for n, f := range failures {
+		msgs[n] = f.key + ": " + f.problem.String()
+	}
+	return report, fmt.Errorf("envdoc: bind: %d field(s) failed:\n  %s", len(failures), strings.Join(msgs, "\n  "))
+}
+
+// checkBindTarget checks that dst is a non-nil pointer to a struct.
+func checkBindTarget(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envdoc: Bind needs a non-nil pointer to a struct, got %T", dst)
+	}
+	return nil
+}
+
+// bindFailure is a field that could not be set from its variable.
+type bindFailure struct {
+	key     string
+	present bool
+	problem Problem
+}
+
+// bind sets the env-tagged fields of the struct v.
+func (i *Inspector) bind(v reflect.Value) []bindFailure {
+	ruleMap := keyedRules(i.ruleSet.Rules)
+	var failures []bindFailure
+	for _, sf := range structFields(v.Type(), "", v.Type().Name(), nil, nil) {
+		rule, ok := ruleMap[sf.key]
+		if !ok {
+			rule, _ = fieldRule(sf.key, sf.opts, sf.field)
+		}
+		value, present := i.env.LookupEnv(sf.key)
+		if !present {
+			if rule.Default == nil {
+				continue
+			}
+			value = *rule.Default
+		}
+		err := errors.New("field is not settable")
+		if field, ok := settableField(v, sf.index); ok {
+			err = decodeValue(field, value, rule)
+		}
+		if err != nil {
+			typ := sf.field.Type.String()
+			failures = append(failures, bindFailure{sf.key, present, Problem{
+				Code: CodeBindFailed, Field: "bind", Severity: SeverityError, Expected: typ,
+				Message: fmt.Sprintf("cannot bind to %s (%s): %s", sf.path, typ, err), DocsURL: rule.DocsURL,
+			}})
+		}
+	}
+	return failures
+}
+
+// settableField returns the field of v at index, allocating nil pointers to
+// nested structs on the way.
+func settableField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for n, x := range index {
+		if n > 0 {
+			for v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					if !v.CanSet() {
+						return v, false
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+var errOutOfRange = errors.New("value out of range")
+
+// decodeValue parses value into v according to v's type, using rule for
+// list and map separators. Errors never include the value.
+func decodeValue(v reflect.Value, value string, rule Rule) error {
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		p := reflect.New(t.Elem())
+		if err := decodeValue(p.Elem(), value, rule); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if typ := fieldType(t); typ != "" && typ != TypeString {
+		parsed, err := parseValue(value, typ)
+		if err != nil {
+			return err
+		}
+		switch x := parsed.(type) {
+		case *url.URL:
+			v.Set(reflect.ValueOf(*x))
+		case netip.Addr:
+			if t == ipType {
+				v.Set(reflect.ValueOf(net.IP(x.AsSlice())))
+			} else {
+				v.Set(reflect.ValueOf(x))
+			}
+		case netip.Prefix:
+			if t == ipNetType {
+				mask := net.CIDRMask(x.Bits(), x.Addr().BitLen())
+				v.Set(reflect.ValueOf(net.IPNet{IP: x.Masked().Addr().AsSlice(), Mask: mask}))
+			} else {
+				v.Set(reflect.ValueOf(x))
+			}
+		case time.Duration:
+			v.SetInt(int64(x))
+		default:
+			v.SetBytes([]byte(value))
+		}
+		return nil
+	}
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := tu.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("not a valid %s", t)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := parseValue(value, TypeBool)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b.(bool))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseValue(value, TypeInt)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n.(int64)) {
+			return errOutOfRange
+		}
+		v.SetInt(n.(int64))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseValue(value, TypeInt)
+		if err != nil {
+			return err
+		}
+		if n.(int64) < 0 || v.OverflowUint(uint64(n.(int64))) {
+			return errOutOfRange
+		}
+		v.SetUint(uint64(n.(int64)))
+	case reflect.Float32, reflect.Float64:
+		f, err := parseValue(value, TypeFloat)
+		if err != nil {
+			return err
+		}
+		if v.OverflowFloat(f.(float64)) {
+			return errOutOfRange
+		}
+		v.SetFloat(f.(float64))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(value))
+			return nil
+		}
+		items := splitList(value, rule.listSeparator())
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for n, item := range items {
+			if err := decodeValue(s.Index(n), item, rule.elementRule()); err != nil {
+				return fmt.Errorf("item[%d]: %w", n, err)
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(t)
+		for n, p := range splitMap(value, rule.listSeparator(), rule.kvSeparator()) {
+			if !p.OK {
+				return fmt.Errorf("pair[%d]: missing %q", n, rule.kvSeparator())
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(elem, p.Value, rule.elementRule()); err != nil {
+				return fmt.Errorf("pair[%d]: %w", n, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(p.Key).Convert(t.Key()), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type")
+	}
+	return nil
+}
+
+// addBindFailures records binding failures on the report's results.
+func addBindFailures(report *Report, failures []bindFailure) {
+	for _, f := range failures {
+		idx := -1
+		for n := range report.Results {
+			if report.Results[n].Key == f.key {
+				idx = n
+				break
+			}
+		}
+		if idx < 0 {
+			report.Results = append(report.Results, VarResult{Key: f.key, Present: f.present, Valid: true})
+			report.Summary.Total++
+			if f.present {
+				report.Summary.Present++
+			}
+			report.Summary.Valid++
+			idx = len(report.Results) - 1
+		}
+		vr := &report.Results[idx]
+		if vr.Valid {
+			vr.Valid = false
+			report.Summary.Valid--
+		}
+		vr.Problems = append(vr.Problems, f.problem)
+		report.Summary.Errors++
+	}
+}
@@ -0,0 +1,194 @@
+package envdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindConfig struct {
+	testCommon
+	Port      int             `env:"PORT,required" envdoc:"type=port"`
+	Timeout   time.Duration   `env:"TIMEOUT" envDefault:"30s"`
+	Ratio     *float64        `env:"RATIO"`
+	Debug     bool            `env:"DEBUG"`
+	Public    *url.URL        `env:"PUBLIC_URL"`
+	Peer      netip.Addr      `env:"PEER"`
+	Legacy    net.IP          `env:"LEGACY_IP"`
+	Trusted   *net.IPNet      `env:"TRUSTED"`
+	Flags     json.RawMessage `env:"FLAGS"`
+	Brokers   []string        `env:"BROKERS" envSeparator:";"`
+	Shards    []int8          `env:"SHARDS"`
+	Weights   map[string]int  `env:"WEIGHTS"`
+	Untouched string          `env:"UNTOUCHED"`
+	DB        *struct {
+		Host string `env:"HOST"`
+	} `envPrefix:"DB_"`
+}
+
+func TestBind(t *testing.T) {
+	env := MapEnvReader{
+		"LOG_LEVEL":  "info",
+		"PORT":       "8080",
+		"RATIO":      "0.25",
+		"DEBUG":      "true",
+		"PUBLIC_URL": "https://example.com/app",
+		"PEER":       "10.0.0.1",
+		"LEGACY_IP":  "192.168.1.1",
+		"TRUSTED":    "10.1.2.3/8",
+		"FLAGS":      `{"beta":true}`,
+		"BROKERS":    "k1:9092; k2:9092",
+		"WEIGHTS":    "a=1,b=2",
+		"DB_HOST":    "db.internal",
+	}
+	cfg := bindConfig{Untouched: "keep"}
+	var out bytes.Buffer
+	report, err := Bind(&cfg, WithEnvReader(env), WithConfig(Config{Mode: ModeAllowlist}), WithOutput(&out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.Errors != 0 {
+		t.Errorf("expected no errors, got %+v", report.Summary)
+	}
+	if !strings.Contains(out.String(), "envdoc: key=PORT present=true") {
+		t.Errorf("expected Bind to log the report, got %s", out.String())
+	}
+
+	if cfg.LogLevel != "info" || cfg.Port != 8080 || cfg.Timeout != 30*time.Second || *cfg.Ratio != 0.25 || !cfg.Debug {
+		t.Errorf("unexpected scalars %+v", cfg)
+	}
+	if cfg.Public.Host != "example.com" || cfg.Peer != netip.MustParseAddr("10.0.0.1") || !cfg.Legacy.Equal(net.ParseIP("192.168.1.1")) {
+		t.Errorf("unexpected addresses %+v", cfg)
+	}
+	if cfg.Trusted.String() != "10.0.0.0/8" || string(cfg.Flags) != `{"beta":true}` {
+		t.Errorf("unexpected cidr or json: %v %s", cfg.Trusted, cfg.Flags)
+	}
+	if strings.Join(cfg.Brokers, ",") != "k1:9092,k2:9092" || cfg.Shards != nil || cfg.Weights["b"] != 2 {
+		t.Errorf("unexpected collections %+v", cfg)
+	}
+	if cfg.Untouched != "keep" || cfg.DB == nil || cfg.DB.Host != "db.internal" {
+		t.Errorf("unexpected untouched or nested fields %+v", cfg)
+	}
+}
+
+// looseBindRules returns bindConfig's rules without the SHARDS bounds, as a
+// hand-written rules file might, so a SHARDS value can validate but not fit.
+func looseBindRules(t *testing.T) []Rule {
+	t.Helper()
+	rules, err := RulesFromStruct(&bindConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := range rules {
+		if rules[n].Key == "SHARDS" {
+			rules[n].Min, rules[n].Max = "", ""
+		}
+	}
+	return rules
+}
+
+func TestBind_Failures(t *testing.T) {
+	env := MapEnvReader{"PORT": "8080", "SHARDS": "1,300"}
+	cfg := bindConfig{}
+	rules := looseBindRules(t)
+	i := New(WithEnvReader(env), WithRules(rules), WithConfig(Config{Mode: ModeAllowlist}), WithOutput(&bytes.Buffer{}))
+
+	report, err := i.Bind(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "envdoc: bind: 1 field(s) failed") ||
+		!strings.Contains(err.Error(), "SHARDS: ENV_BIND_FAILED: cannot bind to bindConfig.Shards ([]int8): item[1]: value out of range") {
+		t.Fatalf("expected bind failure, got %v", err)
+	}
+	if strings.Contains(err.Error(), "300") {
+		t.Errorf("bind error echoes the value: %v", err)
+	}
+
+	var shards VarResult
+	for _, r := range report.Results {
+		if r.Key == "SHARDS" {
+			shards = r
+		}
+	}
+	if shards.Valid || len(shards.Problems) != 1 || shards.Problems[0].Code != CodeBindFailed || shards.Problems[0].Expected != "[]int8" {
+		t.Errorf("expected bind problem on SHARDS, got %+v", shards)
+	}
+	if report.Summary.Errors != 1 {
+		t.Errorf("expected 1 error in summary, got %+v", report.Summary)
+	}
+
+	// Later reports, such as /debug/env, keep the binding failure.
+	rec := httptest.NewRecorder()
+	i.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/env", nil))
+	if !strings.Contains(rec.Body.String(), `"code":"ENV_BIND_FAILED"`) {
+		t.Errorf("expected bind failure in /debug/env, got %s", rec.Body.String())
+	}
+}
+
+func TestBind_InvalidReportDoesNotBind(t *testing.T) {
+	cfg := bindConfig{}
+	_, err := Bind(&cfg, WithEnvReader(MapEnvReader{"PORT": "99999", "DEBUG": "true"}), WithOutput(&bytes.Buffer{}))
+	if err == nil || !strings.Contains(err.Error(), "fail-fast") {
+		t.Fatalf("expected fail-fast error, got %v", err)
+	}
+	if cfg.Debug {
+		t.Error("expected no fields to be bound")
+	}
+}
+
+func TestBind_InvalidOptionalNotBound(t *testing.T) {
+	type modeConfig struct {
+		Port  int    `env:"PORT" envdoc:"min=1,max=100"`
+		Mode  string `env:"MODE" envdoc:"allowed=a|b"`
+		Debug bool   `env:"DEBUG"`
+	}
+	env := MapEnvReader{"PORT": "5000", "MODE": "zzz", "DEBUG": "true"}
+	cfg := modeConfig{Port: 1, Mode: "a"}
+	rules, err := RulesFromStruct(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := New(WithEnvReader(env), WithRules(rules), WithConfig(Config{Mode: ModeAllowlist, FailFast: true}), WithOutput(&bytes.Buffer{}))
+
+	report, err := i.Bind(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "fail-fast: 2 variable(s) invalid") ||
+		!strings.Contains(err.Error(), "PORT: ") || !strings.Contains(err.Error(), "MODE: ") {
+		t.Fatalf("expected invalid optional fields to fail, got %v", err)
+	}
+	if strings.Contains(err.Error(), "5000") || strings.Contains(err.Error(), "zzz") {
+		t.Errorf("bind error echoes a value: %v", err)
+	}
+	if cfg.Port != 1 || cfg.Mode != "a" || cfg.Debug {
+		t.Errorf("expected no fields to be bound, got %+v", cfg)
+	}
+	if report.Summary.Errors != 2 {
+		t.Errorf("expected the 2 inspection errors only, got %+v", report.Summary)
+	}
+}
+
+func TestBind_Repeated(t *testing.T) {
+	env := MapEnvReader{"PORT": "8080", "SHARDS": "300"}
+	rules := looseBindRules(t)
+	i := New(WithEnvReader(env), WithRules(rules), WithConfig(Config{Mode: ModeAllowlist}), WithOutput(&bytes.Buffer{}))
+
+	// A second Bind replaces the first one's failures rather than adding to them.
+	for range 2 {
+		report, err := i.Bind(&bindConfig{})
+		if err == nil || !strings.Contains(err.Error(), "1 field(s) failed") || report.Summary.Errors != 1 {
+			t.Fatalf("expected one bind failure, got %v, %+v", err, report.Summary)
+		}
+	}
+}
+
+func TestBind_Target(t *testing.T) {
+	var cfg bindConfig
+	for _, dst := range []any{cfg, (*bindConfig)(nil), new(int)} {
+		if _, err := Bind(dst); err == nil || !strings.Contains(err.Error(), "Bind needs a non-nil pointer to a struct") {
+			t.Errorf("%T: expected target error, got %v", dst, err)
+		}
+	}
+}
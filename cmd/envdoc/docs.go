@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tendant/envdoc"
+)
+
+// runDocs implements "envdoc docs": it renders the rules as Markdown tables
+// or as a commented .env.example.
+func runDocs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envdoc docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesPath := fs.String("rules", "", "path to rules file (YAML, JSON or TOML)")
+	format := fs.String("format", "markdown", "output format: markdown or env")
+	profile := fs.String("profile", "", "rules profile to apply before rendering")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rulesPath == "" {
+		fmt.Fprintln(stderr, "envdoc docs: -rules is required")
+		return 2
+	}
+	var write func(io.Writer, []envdoc.Rule) error
+	switch *format {
+	case "markdown":
+		write = envdoc.WriteMarkdown
+	case "env":
+		write = envdoc.WriteEnvExample
+	default:
+		fmt.Fprintf(stderr, "envdoc docs: unknown -format %q (want markdown or env)\n", *format)
+		return 2
+	}
+
+	rs, err := envdoc.LoadRuleSetFile(*rulesPath)
+	if err == nil {
+		err = rs.ApplyProfile(*profile)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc docs: %v\n", err)
+		return 1
+	}
+	var buf bytes.Buffer
+	write(&buf, rs.Rules)
+
+	if *out == "" {
+		stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(stderr, "envdoc docs: %v\n", err)
+		return 1
+	}
+	return 0
+}
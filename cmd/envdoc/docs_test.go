@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runDocs([]string{"-rules", "testdata/docs/rules.yaml"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"## API\n",
+		"| [`API_URL`](https://example.com/docs/api-url) | url | required |  | `https://api.example.com` |  | Base URL of the upstream API. |\n",
+		"| `API_TOKEN` | string | required |  | *secret* |  |  |\n",
+		"## Logging\n",
+		"| `LOG_LEVEL` | string | optional | `info` |  | one of `debug`, `info`, `warn` |  |\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestDocs_EnvExample(t *testing.T) {
+	out := filepath.Join(t.TempDir(), ".env.example")
+	var stderr bytes.Buffer
+	if code := runDocs([]string{"-rules", "testdata/docs/rules.yaml", "-format", "env", "-o", out}, &bytes.Buffer{}, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# --- API ---
+
+# Base URL of the upstream API.
+# url; required; see https://example.com/docs/api-url
+API_URL=https://api.example.com
+
+# string; required; secret
+API_TOKEN=<secret>
+
+# --- Logging ---
+
+# string; optional; one of debug, info, warn; default info
+# LOG_LEVEL=info
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDocs_Usage(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{}, 2, "-rules is required"},
+		{[]string{"-rules", "testdata/docs/rules.yaml", "-format", "html"}, 2, `unknown -format "html"`},
+		{[]string{"-rules", "../../testdata/invalid_regex.yaml"}, 1, "invalid regex"},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		code := runDocs(tt.args, &bytes.Buffer{}, &stderr)
+		if code != tt.code || !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%v: got exit %d, %q; want exit %d, %q", tt.args, code, stderr.String(), tt.code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/tendant/envdoc"
+	"gopkg.in/yaml.v3"
+)
+
+// runGen implements "envdoc gen": it writes a Go package with a typed
+// Config struct, a constant per key and a Load function that validates and
+// binds with the same rules.
+func runGen(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envdoc gen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesPath := fs.String("rules", "", "path to rules file (YAML, JSON or TOML)")
+	pkg := fs.String("pkg", "config", "name of the generated package")
+	profile := fs.String("profile", "", "rules profile to apply before generating")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rulesPath == "" {
+		fmt.Fprintln(stderr, "envdoc gen: -rules is required")
+		return 2
+	}
+
+	rs, err := envdoc.LoadRuleSetFile(*rulesPath)
+	if err == nil {
+		err = rs.ApplyProfile(*profile)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc gen: %v\n", err)
+		return 1
+	}
+	src, err := generate(rs, *pkg, *rulesPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc gen: %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		stdout.Write(src)
+		return 0
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(stderr, "envdoc gen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// genField is a Config field generated for a rule.
+type genField struct {
+	Name string
+	Key  string
+	Type string
+}
+
+// generate renders the Go source of package pkg for rs. source names the
+// rules file in the header.
+func generate(rs *envdoc.RuleSet, pkg, source string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	var fields []genField
+	imports := make(map[string]bool)
+	owners := make(map[string]string)
+	for _, r := range rs.Rules {
+		if r.Key == "" {
+			// Pattern rules validate a family of variables but get no field.
+			continue
+		}
+		name, err := goName(r.Key)
+		if err != nil {
+			return nil, err
+		}
+		if other, dup := owners[name]; dup {
+			return nil, fmt.Errorf("keys %s and %s both map to Go name %s", other, r.Key, name)
+		}
+		owners[name] = r.Key
+		typ := goType(r.Type, r.ItemType, imports)
+		fields = append(fields, genField{Name: name, Key: r.Key, Type: typ})
+	}
+
+	// The rules are embedded as resolved: includes, overrides, schema files
+	// and the profile are already applied.
+	rules, err := yaml.Marshal(envdoc.RuleSet{Rules: rs.Rules, Groups: rs.Groups, Assertions: rs.Assertions})
+	if err != nil {
+		return nil, err
+	}
+	lit := "`" + string(rules) + "`"
+	if strings.ContainsAny(string(rules), "`\r") {
+		lit = strconv.Quote(string(rules))
+	}
+
+	var buf bytes.Buffer
+	err = genTemplate.Execute(&buf, map[string]any{
+		"Package": pkg,
+		"Source":  source,
+		"Profile": rs.ActiveProfile,
+		"Imports": slices.Sorted(maps.Keys(imports)),
+		"Fields":  fields,
+		"Rules":   lit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// goType returns the Go type a value of rule type typ is bound to, adding
+// the standard packages it needs to imports. Custom and text types are strings.
+func goType(typ, item envdoc.VarType, imports map[string]bool) string {
+	switch typ {
+	case envdoc.TypeInt, envdoc.TypePort:
+		return "int"
+	case envdoc.TypeFloat:
+		return "float64"
+	case envdoc.TypeBool:
+		return "bool"
+	case envdoc.TypeDuration:
+		imports["time"] = true
+		return "time.Duration"
+	case envdoc.TypeURL:
+		imports["net/url"] = true
+		return "*url.URL"
+	case envdoc.TypeIP:
+		imports["net/netip"] = true
+		return "netip.Addr"
+	case envdoc.TypeCIDR:
+		imports["net/netip"] = true
+		return "netip.Prefix"
+	case envdoc.TypeJSON:
+		imports["encoding/json"] = true
+		return "json.RawMessage"
+	case envdoc.TypeList:
+		return "[]" + goType(item, "", imports)
+	case envdoc.TypeMap:
+		return "map[string]" + goType(item, "", imports)
+	}
+	return "string"
+}
+
+// initialisms are name parts kept in upper case, as in Go identifiers.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "QPS": true, "RAM": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// goName converts an environment variable name to an exported Go name:
+// DB_HOST -> DbHost, API_URL -> APIURL.
+func goName(key string) (string, error) {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		p = strings.ToUpper(p)
+		if !initialisms[p] {
+			p = p[:1] + strings.ToLower(p[1:])
+		}
+		b.WriteString(p)
+	}
+	name := b.String()
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return "", fmt.Errorf("cannot derive a Go name from key %q", key)
+	}
+	return name, nil
+}
+
+var genTemplate = template.Must(template.New("gen").Parse(`// Code generated by envdoc gen from {{.Source}}{{with .Profile}} (profile {{.}}){{end}}; DO NOT EDIT.
+
+// Package {{.Package}} holds the typed environment described by its rules.
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+
+	"github.com/tendant/envdoc"
+)
+
+// Environment variable names.
+const (
+{{- range .Fields}}
+	Env{{.Name}} = "{{.Key}}"
+{{- end}}
+)
+
+// Config holds the environment, decoded by Load.
+type Config struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`env:\"{{.Key}}\"`" + `
+{{- end}}
+}
+
+// rules are the validation rules Config was generated from.
+const rules = {{.Rules}}
+
+// NewInspector returns an Inspector with the generated rules. opts are
+// applied after them.
+func NewInspector(opts ...envdoc.Option) (*envdoc.Inspector, error) {
+	rs, err := envdoc.LoadRuleSet([]byte(rules))
+	if err != nil {
+		return nil, err
+	}
+	return envdoc.New(append([]envdoc.Option{envdoc.WithRuleSet(rs)}, opts...)...), nil
+}
+
+// Load inspects the environment, logs the report and, if it passes the
+// fail-fast check, decodes it into a Config. See envdoc.Inspector.Bind.
+func Load(opts ...envdoc.Option) (*Config, *envdoc.Report, error) {
+	i, err := NewInspector(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg Config
+	report, err := i.Bind(&cfg)
+	if err != nil {
+		return nil, report, err
+	}
+	return &cfg, report, nil
+}
+`))
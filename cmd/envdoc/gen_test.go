@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tendant/envdoc"
+)
+
+const genRules = `
+rules:
+  - key: DB_HOST
+    required: true
+    type: hostname
+  - key: HTTP_PORT
+    type: port
+    default: 8080
+  - key: REQUEST_TIMEOUT
+    type: duration
+  - key: API_URL
+    type: url
+  - key: BROKERS
+    type: list
+    item_type: hostport
+    separator: ";"
+  - key: LIMITS
+    type: map
+    item_type: int
+  - key_pattern: FEATURE_*
+    type: bool
+`
+
+const genMain = `package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tendant/envdoc"
+	"gentest/config"
+)
+
+func main() {
+	cfg, _, err := config.Load(envdoc.WithOutput(io.Discard))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(config.EnvDbHost, cfg.DbHost, cfg.HTTPPort, cfg.RequestTimeout, cfg.APIURL.Host, cfg.Brokers, cfg.Limits["rps"])
+}
+`
+
+func TestGen(t *testing.T) {
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(rulesPath, []byte(genRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated package is built in a module of its own that replaces
+	// envdoc with this checkout, so nothing is written to the source tree.
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgDir := filepath.Join(dir, "gentest")
+	if err := os.MkdirAll(filepath.Join(pkgDir, "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	goMod := fmt.Sprintf("module gentest\n\ngo 1.25.1\n\nrequire github.com/tendant/envdoc v0.0.0\n\nreplace github.com/tendant/envdoc => %s\n", root)
+	if err := os.WriteFile(filepath.Join(pkgDir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "go.sum"), goSum, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	out := filepath.Join(pkgDir, "config", "config.go")
+	if code := runGen([]string{"-rules", rulesPath, "-o", out}, &bytes.Buffer{}, &stderr); code != 0 {
+		t.Fatalf("gen exited %d: %s", code, stderr.String())
+	}
+	src, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Compare with runs of spaces collapsed, as gofmt aligns the columns.
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"// Code generated by envdoc gen",
+		`EnvHTTPPort = "HTTP_PORT"`,
+		"RequestTimeout time.Duration `env:\"REQUEST_TIMEOUT\"`",
+		"APIURL *url.URL `env:\"API_URL\"`",
+		"Brokers []string `env:\"BROKERS\"`",
+		"Limits map[string]int `env:\"LIMITS\"`",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(code, "EnvFeature") {
+		t.Errorf("expected no constant for a pattern rule:\n%s", src)
+	}
+
+	if err := os.WriteFile(filepath.Join(pkgDir, "main.go"), []byte(genMain), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "gentest.bin")
+	build := exec.Command("go", "build", "-mod=mod", "-o", bin, ".")
+	build.Dir = pkgDir
+	if got, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("generated package does not build: %v\n%s", err, got)
+	}
+
+	run := exec.Command(bin)
+	run.Env = append(os.Environ(),
+		"DB_HOST=db.internal",
+		"REQUEST_TIMEOUT=5s",
+		"API_URL=https://api.example.com/v1",
+		"BROKERS=k1:9092;k2:9092",
+		"LIMITS=rps=50",
+	)
+	got, err := run.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated package failed: %v\n%s", err, got)
+	}
+	if want := "DB_HOST db.internal 8080 5s api.example.com [k1:9092 k2:9092] 50\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	bad := exec.Command(bin)
+	bad.Env = append(os.Environ(), "DB_HOST=-bad-")
+	got, err = bad.CombinedOutput()
+	if err == nil || !strings.Contains(string(got), "fail-fast") {
+		t.Errorf("expected Load to fail validation, got %v\n%s", err, got)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []envdoc.Rule
+		pkg   string
+		want  string
+	}{
+		{"bad package", []envdoc.Rule{{Key: "A"}}, "my-config", `invalid package name "my-config"`},
+		{"bad key", []envdoc.Rule{{Key: "9LIVES"}}, "config", `cannot derive a Go name from key "9LIVES"`},
+		{"name clash", []envdoc.Rule{{Key: "API_KEY"}, {Key: "API__KEY"}}, "config", "keys API_KEY and API__KEY both map to Go name APIKey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate(&envdoc.RuleSet{Rules: tt.rules}, tt.pkg, "rules.yaml")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for key, want := range map[string]string{
+		"DB_HOST": "DbHost", "API_URL": "APIURL", "S3_BUCKET": "S3Bucket", "http.proxy": "HTTPProxy", "log_level": "LogLevel",
+	} {
+		if got, err := goName(key); err != nil || got != want {
+			t.Errorf("goName(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/tendant/envdoc"
+)
+
+// runLint implements "envdoc lint": it loads a rules file, prints the
+// findings of envdoc.LintRules and exits 1 when any is at or above the
+// -fail-on severity.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envdoc lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesPath := fs.String("rules", "", "path to rules file (YAML, JSON or TOML)")
+	profile := fs.String("profile", "", "rules profile to apply before linting")
+	failOn := fs.String("fail-on", "error", "lowest finding severity that fails: error, warning or info")
+	asJSON := fs.Bool("json", false, "print findings as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rulesPath == "" {
+		fmt.Fprintln(stderr, "envdoc lint: -rules is required")
+		return 2
+	}
+	threshold, err := envdoc.ParseSeverity(*failOn)
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc lint: -fail-on: %v\n", err)
+		return 2
+	}
+
+	rs, err := envdoc.LoadRuleSetFile(*rulesPath)
+	if err == nil {
+		err = rs.ApplyProfile(*profile)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc lint: %v\n", err)
+		return 1
+	}
+
+	findings := envdoc.LintRules(rs.Rules)
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if findings == nil {
+			findings = []envdoc.Finding{}
+		}
+		enc.Encode(findings)
+	} else {
+		for _, f := range findings {
+			fmt.Fprintf(stdout, "%s: %s\n", *rulesPath, f)
+		}
+	}
+
+	for _, f := range findings {
+		if f.Severity.AtLeast(threshold) {
+			return 1
+		}
+	}
+	return 0
+}
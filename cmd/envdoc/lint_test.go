@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tendant/envdoc"
+)
+
+func TestLint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runLint([]string{"-rules", "testdata/lint/rules.yaml"}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("expected exit 1 for an error finding, got %d: %s", code, stderr.String())
+	}
+	want := `testdata/lint/rules.yaml: rule[1] (DEBUG): warning LINT_ALLOWED_INVALID: allowed value "yes" can never be valid: not a valid int
+testdata/lint/rules.yaml: rule[1] (DEBUG): warning LINT_ALLOWED_INVALID: allowed value "no" can never be valid: not a valid int
+testdata/lint/rules.yaml: rule[1] (DEBUG): error LINT_UNSATISFIABLE: no allowed value passes the rule's other checks
+testdata/lint/rules.yaml: rule[2] (API_TOKEN): warning LINT_SECRET_FINGERPRINT: fingerprint is enabled on a secret; short or guessable secrets can be recovered from it
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestLint_JSON(t *testing.T) {
+	var stdout bytes.Buffer
+	runLint([]string{"-rules", "testdata/lint/rules.yaml", "-json"}, &stdout, &bytes.Buffer{})
+	var findings []envdoc.Finding
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 4 || findings[2].Code != envdoc.LintUnsatisfiable || findings[2].Field != "allowed" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
+
+func TestLint_FailOn(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"-rules", "../../testdata/basic_rules.yaml"}, 0, ""},
+		{[]string{"-rules", "../../testdata/basic_rules.yaml", "-fail-on", "warning"}, 1, ""},
+		{[]string{"-rules", "../../testdata/basic_rules.yaml", "-fail-on", "fatal"}, 2, `unknown severity "fatal"`},
+		{[]string{}, 2, "-rules is required"},
+		{[]string{"-rules", "../../testdata/invalid_regex.yaml"}, 1, "invalid regex"},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		code := runLint(tt.args, &bytes.Buffer{}, &stderr)
+		if code != tt.code || !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%v: got exit %d, %q; want exit %d, %q", tt.args, code, stderr.String(), tt.code, tt.want)
+		}
+	}
+}
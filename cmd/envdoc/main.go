@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,10 +15,27 @@ import (
 
 var version = "dev"
 
+// subcommands maps subcommand names to their implementations, which take
+// the arguments after the name and return the exit code.
+var subcommands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"docs":   runDocs,
+	"gen":    runGen,
+	"lint":   runLint,
+	"scan":   runScan,
+	"schema": runSchema,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	showVersion := flag.Bool("version", false, "print version and exit")
-	rulesPath := flag.String("rules", "", "path to YAML rules file")
+	rulesPath := flag.String("rules", "", "path to rules file (YAML, JSON or TOML)")
 	listenAddr := flag.String("listen", "", "HTTP listen address (overrides ENVDOC_LISTEN_ADDR)")
+	profile := flag.String("profile", "", "rules profile to apply (overrides ENVDOC_PROFILE)")
 	flag.Parse()
 
 	if *showVersion {
@@ -25,15 +43,28 @@ func main() {
 		return
 	}
 
-	var opts []envdoc.Option
+	cfg := envdoc.LoadConfig(envdoc.OSEnv())
+	if *profile != "" {
+		if *rulesPath == "" {
+			fmt.Fprintln(os.Stderr, "envdoc: -profile requires -rules")
+			os.Exit(2)
+		}
+		cfg.Profile = *profile
+	}
+
+	opts := []envdoc.Option{envdoc.WithConfig(cfg)}
 
 	if *rulesPath != "" {
-		rules, err := envdoc.LoadRulesFile(*rulesPath)
+		rs, err := envdoc.LoadRuleSetFile(*rulesPath)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "envdoc: %v\n", err)
 			os.Exit(1)
 		}
-		opts = append(opts, envdoc.WithRules(rules))
+		if err := rs.ApplyProfile(cfg.Profile); err != nil {
+			fmt.Fprintf(os.Stderr, "envdoc: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, envdoc.WithRuleSet(rs))
 	}
 
 	inspector := envdoc.New(opts...)
@@ -43,7 +74,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	cfg := inspector.Config()
+	cfg = inspector.Config()
 	if cfg.EnableHTTP {
 		addr := *listenAddr
 		if addr == "" {
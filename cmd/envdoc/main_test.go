@@ -93,3 +93,25 @@ func TestCLI_InvalidRulesPath(t *testing.T) {
 		t.Errorf("expected error message: %s", out)
 	}
 }
+
+func TestCLI_Gen(t *testing.T) {
+	binPath := t.TempDir() + "/envdoc"
+	cmd := exec.Command("go", "build", "-o", binPath, ".")
+	cmd.Dir = "."
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+
+	out, err := exec.Command(binPath, "gen", "-rules", "../../testdata/basic_rules.yaml", "-pkg", "settings").CombinedOutput()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "package settings") || !strings.Contains(string(out), `EnvDbPassword   = "DB_PASSWORD"`) {
+		t.Errorf("expected generated package: %s", out)
+	}
+
+	out, err = exec.Command(binPath, "gen").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "envdoc gen: -rules is required") {
+		t.Errorf("expected usage error, got %v: %s", err, out)
+	}
+}
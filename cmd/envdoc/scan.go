@@ -0,0 +1,365 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tendant/envdoc"
+	"gopkg.in/yaml.v3"
+)
+
+// runScan implements "envdoc scan": it lists the environment variables Go
+// source reads through os.Getenv, os.LookupEnv and syscall.Getenv and, with
+// -rules, compares them with the rules. It exits 1 when a read key has no
+// rule, unless -scaffold asks for rules for those keys instead.
+func runScan(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envdoc scan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesPath := fs.String("rules", "", "rules file to compare with (YAML, JSON or TOML)")
+	scaffold := fs.Bool("scaffold", false, "print rules for read but undeclared keys")
+	tests := fs.Bool("tests", false, "scan _test.go files too")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *scaffold && *rulesPath == "" {
+		fmt.Fprintln(stderr, "envdoc scan: -scaffold requires -rules")
+		return 2
+	}
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	s := &scanner{fset: token.NewFileSet(), tests: *tests}
+	for _, p := range patterns {
+		if err := s.scanPattern(p); err != nil {
+			fmt.Fprintf(stderr, "envdoc scan: %v\n", err)
+			return 1
+		}
+	}
+
+	if *rulesPath == "" {
+		for _, r := range s.reads {
+			fmt.Fprintf(stdout, "%s\t%s\n", r.pos, r.key)
+		}
+		printDynamic(stdout, s.dynamic)
+		return 0
+	}
+
+	rs, err := envdoc.LoadRuleSetFile(*rulesPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "envdoc scan: %v\n", err)
+		return 1
+	}
+	d := diffReads(s.reads, rs.Rules)
+	if *scaffold {
+		out, err := scaffoldRules(d.undeclared)
+		if err != nil {
+			fmt.Fprintf(stderr, "envdoc scan: %v\n", err)
+			return 1
+		}
+		stdout.Write(out)
+		return 0
+	}
+
+	if len(d.undeclared) > 0 {
+		fmt.Fprintln(stdout, "read but undeclared:")
+		for _, r := range d.undeclared {
+			fmt.Fprintf(stdout, "  %s\t%s\n", r.pos, r.key)
+		}
+	}
+	if len(d.unread) > 0 {
+		fmt.Fprintln(stdout, "declared but never read:")
+		for _, key := range d.unread {
+			fmt.Fprintf(stdout, "  %s\n", key)
+		}
+	}
+	printDynamic(stdout, s.dynamic)
+	if len(d.undeclared) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printDynamic lists reads whose key could not be resolved.
+func printDynamic(w io.Writer, dynamic []envRead) {
+	if len(dynamic) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "dynamic keys:")
+	for _, r := range dynamic {
+		fmt.Fprintf(w, "  %s\t%s\n", r.pos, r.key)
+	}
+}
+
+// envRead is a call that reads an environment variable. key is the
+// resolved name or, for dynamic reads, the call as written.
+type envRead struct {
+	key string
+	pos token.Position
+}
+
+// envFuncs are the functions, by import path, whose first argument is an
+// environment variable name.
+var envFuncs = map[string][]string{
+	"os":      {"Getenv", "LookupEnv"},
+	"syscall": {"Getenv"},
+}
+
+// scanner collects environment reads from Go source files.
+type scanner struct {
+	fset    *token.FileSet
+	tests   bool
+	reads   []envRead
+	dynamic []envRead
+}
+
+// scanPattern scans a file, a directory, or a directory and its
+// subdirectories when the pattern ends in "/...". Like the go command, it
+// skips vendor and testdata directories and those starting with . or _.
+func (s *scanner) scanPattern(pattern string) error {
+	root, recursive := strings.CutSuffix(pattern, "/...")
+	if root == "..." {
+		root, recursive = ".", true
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return s.scanFiles([]string{root})
+	}
+	if !recursive {
+		return s.scanDir(root)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		return s.scanDir(path)
+	})
+}
+
+// scanDir scans the Go files of a single directory.
+func (s *scanner) scanDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || (!s.tests && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return s.scanFiles(files)
+}
+
+// scanFiles parses files, which belong to one directory, and records their
+// environment reads. Package-level string constants declared in any of the
+// files are resolved.
+func (s *scanner) scanFiles(files []string) error {
+	parsed := make([]*ast.File, 0, len(files))
+	for _, name := range files {
+		f, err := parser.ParseFile(s.fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, f)
+	}
+
+	// Constants are keyed by package so files of an external test package
+	// do not see the package under test.
+	consts := make(map[string]map[string]ast.Expr)
+	for _, f := range parsed {
+		pkg := f.Name.Name
+		if consts[pkg] == nil {
+			consts[pkg] = make(map[string]ast.Expr)
+		}
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						consts[pkg][name.Name] = vs.Values[i]
+					}
+				}
+			}
+		}
+	}
+
+	for _, f := range parsed {
+		funcs := importedEnvFuncs(f)
+		if len(funcs) == 0 {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok || !funcs[pkg.Name+"."+sel.Sel.Name] {
+				return true
+			}
+			read := envRead{pos: s.fset.Position(call.Pos())}
+			if key, ok := constString(call.Args[0], consts[f.Name.Name], 0); ok {
+				read.key = key
+				s.reads = append(s.reads, read)
+			} else {
+				read.key = exprString(s.fset, call)
+				s.dynamic = append(s.dynamic, read)
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// importedEnvFuncs returns the environment functions f can call, as
+// "name.Func" under the names their packages are imported as.
+func importedEnvFuncs(f *ast.File) map[string]bool {
+	funcs := make(map[string]bool)
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || envFuncs[path] == nil {
+			continue
+		}
+		name := path
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		for _, fn := range envFuncs[path] {
+			funcs[name+"."+fn] = true
+		}
+	}
+	return funcs
+}
+
+// constString evaluates e as a constant string: a string literal, a
+// package-level constant, a parenthesized expression or a concatenation of
+// those. depth guards against constants defined in terms of each other.
+func constString(e ast.Expr, consts map[string]ast.Expr, depth int) (string, bool) {
+	if depth > 10 {
+		return "", false
+	}
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		if v, ok := consts[e.Name]; ok {
+			return constString(v, consts, depth+1)
+		}
+	case *ast.ParenExpr:
+		return constString(e.X, consts, depth+1)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := constString(e.X, consts, depth+1)
+		if !ok {
+			return "", false
+		}
+		y, ok := constString(e.Y, consts, depth+1)
+		return x + y, ok
+	}
+	return "", false
+}
+
+// exprString renders e as written in the source.
+func exprString(fset *token.FileSet, e ast.Expr) string {
+	start, end := fset.Position(e.Pos()), fset.Position(e.End())
+	src, err := os.ReadFile(start.Filename)
+	if err != nil || end.Offset > len(src) {
+		return "(unknown)"
+	}
+	return string(src[start.Offset:end.Offset])
+}
+
+// readDiff compares environment reads with rules.
+type readDiff struct {
+	undeclared []envRead // reads of keys no rule matches
+	unread     []string  // rule keys no read matches
+}
+
+// diffReads compares reads with rules. A read is declared when any rule,
+// including a pattern rule, matches its key. Pattern rules are never
+// reported as unread, since the keys they cover may all be read
+// dynamically.
+func diffReads(reads []envRead, rules []envdoc.Rule) readDiff {
+	var d readDiff
+	read := make(map[string]bool)
+	for _, r := range reads {
+		read[r.key] = true
+		if !slices.ContainsFunc(rules, func(rule envdoc.Rule) bool { return rule.Matches(r.key) }) {
+			d.undeclared = append(d.undeclared, r)
+		}
+	}
+	for _, rule := range rules {
+		if rule.Key != "" && !read[rule.Key] {
+			d.unread = append(d.unread, rule.Key)
+		}
+	}
+	return d
+}
+
+// scaffoldRules renders a rules file entry for each undeclared key, with
+// the places it is read as a comment. The type is left as string.
+func scaffoldRules(reads []envRead) ([]byte, error) {
+	var keys []string
+	where := make(map[string][]string)
+	for _, r := range reads {
+		if where[r.key] == nil {
+			keys = append(keys, r.key)
+		}
+		where[r.key] = append(where[r.key], r.pos.String())
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, key := range keys {
+		var n yaml.Node
+		if err := n.Encode(envdoc.Rule{Key: key, Type: envdoc.TypeString}); err != nil {
+			return nil, err
+		}
+		n.HeadComment = "read at " + strings.Join(where[key], ", ")
+		seq.Content = append(seq.Content, &n)
+	}
+	doc := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "rules"}, seq,
+	}}
+	return yaml.Marshal(doc)
+}
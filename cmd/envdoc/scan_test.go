@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runScan([]string{"testdata/scan/app/..."}, &stdout, &stderr); code != 0 {
+		t.Fatalf("scan exited %d: %s", code, stderr.String())
+	}
+	want := `testdata/scan/app/main.go:14:10	DB_HOST
+testdata/scan/app/main.go:15:13	APP_PORT
+testdata/scan/app/main.go:16:13	HOME
+testdata/scan/app/main.go:19:12	FEATURE_SEARCH
+testdata/scan/app/sub/sub.go:7:9	DB_HOST
+dynamic keys:
+  testdata/scan/app/main.go:18:9	os.Getenv("FEATURE_" + name)
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestScan_Rules(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runScan([]string{"-rules", "testdata/scan/rules.yaml", "-tests", "testdata/scan/app/..."}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("expected exit 1 for undeclared reads, got %d: %s", code, stderr.String())
+	}
+	want := `read but undeclared:
+  testdata/scan/app/main.go:15:13	APP_PORT
+  testdata/scan/app/main.go:16:13	HOME
+  testdata/scan/app/main_test.go:9:5	TEST_ONLY
+declared but never read:
+  UNUSED
+dynamic keys:
+  testdata/scan/app/main.go:18:9	os.Getenv("FEATURE_" + name)
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestScan_Scaffold(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runScan([]string{"-rules", "testdata/scan/rules.yaml", "-scaffold", "testdata/scan/app"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("scan exited %d: %s", code, stderr.String())
+	}
+	want := `rules:
+    # read at testdata/scan/app/main.go:15:13
+    - key: APP_PORT
+      required: false
+      type: string
+    # read at testdata/scan/app/main.go:16:13
+    - key: HOME
+      required: false
+      type: string
+`
+	if stdout.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+}
+
+func TestScan_Errors(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"-scaffold"}, 2, "-scaffold requires -rules"},
+		{[]string{"testdata/missing/..."}, 1, "no such file or directory"},
+		{[]string{"-rules", "testdata/missing.yaml", "testdata/scan/app"}, 1, "missing.yaml"},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		code := runScan(tt.args, &bytes.Buffer{}, &stderr)
+		if code != tt.code || !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%v: got exit %d, %q; want exit %d, %q", tt.args, code, stderr.String(), tt.code, tt.want)
+		}
+	}
+}
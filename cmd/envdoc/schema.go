@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tendant/envdoc"
+)
+
+// runSchema implements "envdoc schema": it prints the JSON Schema of rules
+// files for editors.
+func runSchema(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("envdoc schema", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var buf bytes.Buffer
+	if err := envdoc.WriteRuleSetSchema(&buf); err != nil {
+		fmt.Fprintf(stderr, "envdoc schema: %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(stderr, "envdoc schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runSchema(nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	shipped, err := os.ReadFile("../../rules.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stdout.Bytes(), shipped) {
+		t.Error("expected the shipped rules.schema.json")
+	}
+	if code := runSchema([]string{"-bogus"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 2 {
+		t.Errorf("expected exit 2 for an unknown flag, got %d", code)
+	}
+}
@@ -0,0 +1,5 @@
+package skip
+
+import "os"
+
+var skipped = os.Getenv("SKIPPED")
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	sys "syscall"
+)
+
+const prefix = "APP_"
+
+const keyPort = prefix + "PORT"
+
+func main() {
+	host := os.Getenv("DB_HOST")
+	port, _ := os.LookupEnv(keyPort)
+	home, _ := sys.Getenv("HOME")
+	name := "SEARCH"
+	dyn := os.Getenv("FEATURE_" + name)
+	search := os.Getenv("FEATURE_SEARCH")
+	fmt.Println(host, port, home, dyn, search)
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_ONLY") != "" {
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
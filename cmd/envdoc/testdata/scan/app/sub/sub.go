@@ -0,0 +1,8 @@
+package sub
+
+import "os"
+
+// Host returns the database host.
+func Host() string {
+	return os.Getenv(`DB_HOST`)
+}
@@ -0,0 +1,186 @@
+package envdoc
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// CompareOp is a cross-variable comparison operator.
+type CompareOp string
+
+const (
+	CompareLT       CompareOp = "lt"
+	CompareLE       CompareOp = "le"
+	CompareEQ       CompareOp = "eq"
+	CompareNE       CompareOp = "ne"
+	CompareSameHost CompareOp = "same_host"
+)
+
+// Comparison relates the rule's variable to another variable: the rule's
+// value must be Op the value of Key. Both values are parsed with their rule
+// types before comparing. The comparison is skipped unless both variables
+// are set and parse.
+type Comparison struct {
+	Op  CompareOp `yaml:"op" json:"op"`
+	Key string    `yaml:"key" json:"key"`
+}
+
+// opSymbols renders ops in problem messages.
+var opSymbols = map[CompareOp]string{
+	CompareLT: "<", CompareLE: "<=", CompareEQ: "==", CompareNE: "!=",
+}
+
+// hostTypes are the VarTypes same_host can extract a host from.
+var hostTypes = map[VarType]bool{
+	TypeURL: true, TypeHostPort: true, TypeHostname: true, TypeIP: true,
+}
+
+// compareClass groups types whose parsed values can be compared with each
+// other. Ports and ints both parse to int64.
+func compareClass(typ VarType) VarType {
+	switch typ {
+	case TypePort:
+		return TypeInt
+	case "":
+		return TypeString
+	}
+	return typ
+}
+
+// describe renders the comparison for problem messages using key names only.
+func (c Comparison) describe(key string) string {
+	if c.Op == CompareSameHost {
+		return fmt.Sprintf("%s and %s must have the same host", key, c.Key)
+	}
+	return fmt.Sprintf("%s must be %s %s", key, opSymbols[c.Op], c.Key)
+}
+
+// evalComparison reports whether a op b holds for raw values a and b of the
+// given rules. ok is false when either value does not parse.
+func evalComparison(op CompareOp, a string, ra Rule, b string, rb Rule) (holds, ok bool) {
+	if op == CompareSameHost {
+		pa, errA := parseValue(a, ra.Type)
+		pb, errB := parseValue(b, rb.Type)
+		if errA != nil || errB != nil {
+			return false, false
+		}
+		return hostOf(pa) == hostOf(pb), true
+	}
+
+	if !orderedTypes[ra.Type] {
+		// eq/ne on unordered types
+		if checkType(a, ra.Type, nil) != nil || checkType(b, rb.Type, nil) != nil {
+			return false, false
+		}
+		eq := valuesEqual(a, b, ra.Type)
+		return eq == (op == CompareEQ), true
+	}
+
+	pa, errA := parseValue(a, ra.Type)
+	pb, errB := parseValue(b, rb.Type)
+	if errA != nil || errB != nil {
+		return false, false
+	}
+	c := compareParsed(pa, pb)
+	switch op {
+	case CompareLT:
+		return c < 0, true
+	case CompareLE:
+		return c <= 0, true
+	case CompareEQ:
+		return c == 0, true
+	case CompareNE:
+		return c != 0, true
+	}
+	return false, false
+}
+
+// hostOf extracts a normalized host from a parsed url, hostport, hostname or ip.
+func hostOf(v any) string {
+	var host string
+	switch x := v.(type) {
+	case *url.URL:
+		host = x.Hostname()
+	case hostPort:
+		host = x.Host
+	case netip.Addr:
+		host = x.String()
+	case string:
+		host = x
+	}
+	return strings.Trim(strings.ToLower(host), ".")
+}
+
+// applyComparisons evaluates every rule's comparisons against env and adds a
+// problem to both variables' results when one fails.
+func applyComparisons(env EnvReader, results []VarResult, rules []Rule, ruleMap map[string]Rule) {
+	index := make(map[string]int, len(results))
+	for i, r := range results {
+		index[r.Key] = i
+	}
+	for _, r := range rules {
+		for _, c := range r.Compare {
+			a, okA := env.LookupEnv(r.Key)
+			b, okB := env.LookupEnv(c.Key)
+			if !okA || !okB {
+				continue
+			}
+			holds, ok := evalComparison(c.Op, a, r, b, ruleMap[c.Key])
+			if !ok || holds {
+				continue
+			}
+			p := newProblem(CodeCompareFailed, "compare", c, "%s", c.describe(r.Key))
+			for _, key := range []string{r.Key, c.Key} {
+				if i, found := index[key]; found {
+					results[i].addProblem(r, p)
+				}
+			}
+		}
+	}
+}
+
+// validateComparisons checks that comparisons reference other known keys
+// with a type the operator can compare.
+func validateComparisons(rules []Rule) error {
+	ruleMap := keyedRules(rules)
+	for idx, r := range rules {
+		for ci, c := range r.Compare {
+			if err := validateComparison(r, c, ruleMap); err != nil {
+				return fmt.Errorf("envdoc: rule[%d] (%s): compare[%d]: %w", idx, r.Key, ci, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateComparison(r Rule, c Comparison, ruleMap map[string]Rule) error {
+	if c.Key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if c.Key == r.Key {
+		return fmt.Errorf("comparison cannot reference its own key")
+	}
+	other, ok := ruleMap[c.Key]
+	if !ok {
+		return fmt.Errorf("unknown key %q", c.Key)
+	}
+	switch c.Op {
+	case CompareLT, CompareLE:
+		if !orderedTypes[r.Type] || compareClass(r.Type) != compareClass(other.Type) {
+			return fmt.Errorf("%s requires both keys to have the same ordered type, got %q and %q", c.Op, r.Type, other.Type)
+		}
+	case CompareEQ, CompareNE:
+		if compareClass(r.Type) != compareClass(other.Type) {
+			return fmt.Errorf("%s requires both keys to have the same type, got %q and %q", c.Op, r.Type, other.Type)
+		}
+	case CompareSameHost:
+		if !hostTypes[r.Type] || !hostTypes[other.Type] {
+			return fmt.Errorf("same_host requires url, hostport, hostname or ip types, got %q and %q", r.Type, other.Type)
+		}
+	default:
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+	return nil
+}
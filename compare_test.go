@@ -0,0 +1,133 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect_CompareOrdered(t *testing.T) {
+	rules := []Rule{
+		{Key: "POOL_MIN", Type: TypeInt, Compare: []Comparison{{Op: CompareLE, Key: "POOL_MAX"}}},
+		{Key: "POOL_MAX", Type: TypeInt},
+		{Key: "READ_TIMEOUT", Type: TypeDuration, Compare: []Comparison{{Op: CompareLT, Key: "REQUEST_TIMEOUT"}}},
+		{Key: "REQUEST_TIMEOUT", Type: TypeDuration},
+	}
+	env := MapEnvReader{
+		"POOL_MIN":        "20",
+		"POOL_MAX":        "10",
+		"READ_TIMEOUT":    "5s",
+		"REQUEST_TIMEOUT": "30s",
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	for _, idx := range []int{0, 1} {
+		r := report.Results[idx]
+		if r.Valid {
+			t.Errorf("%s: expected invalid", r.Key)
+		}
+		if len(r.Problems) != 1 || r.Problems[0].Message != "POOL_MIN must be <= POOL_MAX" {
+			t.Errorf("%s: unexpected problems %v", r.Key, r.Problems)
+		}
+		for _, p := range r.Problems {
+			if strings.Contains(p.Message, "20") || strings.Contains(p.Message, "10") {
+				t.Errorf("%s: problem leaks value: %q", r.Key, p)
+			}
+		}
+	}
+	if !report.Results[2].Valid || !report.Results[3].Valid {
+		t.Error("expected timeouts to be valid")
+	}
+	if report.Summary.Valid != 2 {
+		t.Errorf("expected 2 valid in summary, got %d", report.Summary.Valid)
+	}
+}
+
+func TestInspect_CompareSkippedWhenUnset(t *testing.T) {
+	rules := []Rule{
+		{Key: "POOL_MIN", Type: TypeInt, Compare: []Comparison{{Op: CompareLT, Key: "POOL_MAX"}}},
+		{Key: "POOL_MAX", Type: TypeInt},
+	}
+	report := inspect(MapEnvReader{"POOL_MIN": "5"}, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+	if !report.Results[0].Valid {
+		t.Errorf("expected comparison to be skipped, got %v", report.Results[0].Problems)
+	}
+}
+
+func TestEvalComparison(t *testing.T) {
+	tests := []struct {
+		name string
+		op   CompareOp
+		a    string
+		ra   Rule
+		b    string
+		rb   Rule
+		want bool
+	}{
+		{"eq bool", CompareEQ, "1", Rule{Type: TypeBool}, "true", Rule{Type: TypeBool}, true},
+		{"ne string", CompareNE, "a", Rule{}, "a", Rule{}, false},
+		{"lt port int", CompareLT, "80", Rule{Type: TypePort}, "443", Rule{Type: TypeInt}, true},
+		{"same host", CompareSameHost, "https://App.Example.com/login", Rule{Type: TypeURL}, "app.example.com", Rule{Type: TypeHostname}, true},
+		{"different host", CompareSameHost, "https://app.example.com", Rule{Type: TypeURL}, "api.example.com:443", Rule{Type: TypeHostPort}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := evalComparison(tt.op, tt.a, tt.ra, tt.b, tt.rb)
+			if !ok {
+				t.Fatal("expected values to parse")
+			}
+			if got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRules_Comparisons(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"valid", []Rule{
+			{Key: "A", Type: TypeInt, Compare: []Comparison{{Op: CompareLT, Key: "B"}}},
+			{Key: "B", Type: TypePort},
+		}, ""},
+		{"unknown key", []Rule{
+			{Key: "A", Type: TypeInt, Compare: []Comparison{{Op: CompareLT, Key: "B"}}},
+		}, `unknown key "B"`},
+		{"self", []Rule{
+			{Key: "A", Type: TypeInt, Compare: []Comparison{{Op: CompareEQ, Key: "A"}}},
+		}, "own key"},
+		{"incompatible", []Rule{
+			{Key: "A", Type: TypeInt, Compare: []Comparison{{Op: CompareLE, Key: "B"}}},
+			{Key: "B", Type: TypeDuration},
+		}, "same ordered type"},
+		{"unordered lt", []Rule{
+			{Key: "A", Type: TypeString, Compare: []Comparison{{Op: CompareLT, Key: "B"}}},
+			{Key: "B", Type: TypeString},
+		}, "same ordered type"},
+		{"same_host types", []Rule{
+			{Key: "A", Type: TypeURL, Compare: []Comparison{{Op: CompareSameHost, Key: "B"}}},
+			{Key: "B", Type: TypeInt},
+		}, "same_host requires"},
+		{"unknown op", []Rule{
+			{Key: "A", Compare: []Comparison{{Op: "gt", Key: "B"}}},
+			{Key: "B"},
+		}, `unknown op "gt"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules(tt.rules)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package envdoc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Condition tests the state of another environment variable. With Present
+// set, only presence is checked. With Equals or In, the variable must be set
+// and equal one of the listed values; when the referenced key has a typed
+// rule, values are compared after parsing (so "1" equals "true" for a bool).
+// With none of these set, the condition holds when the variable is set.
+type Condition struct {
+	Key     string   `yaml:"key"`
+	Present *bool    `yaml:"present,omitempty"`
+	Equals  string   `yaml:"equals,omitempty"`
+	In      []string `yaml:"in,omitempty"`
+}
+
+// String describes the condition using only rule data, never env values.
+func (c Condition) String() string {
+	switch {
+	case c.Present != nil && !*c.Present:
+		return c.Key + " is not set"
+	case c.Equals != "":
+		return fmt.Sprintf("%s == %s", c.Key, c.Equals)
+	case len(c.In) > 0:
+		return fmt.Sprintf("%s in [%s]", c.Key, strings.Join(c.In, ", "))
+	}
+	return c.Key + " is set"
+}
+
+// holds evaluates the condition against env, using ruleMap for value types.
+func (c Condition) holds(env EnvReader, ruleMap map[string]Rule) bool {
+	value, present := env.LookupEnv(c.Key)
+	if c.Present != nil {
+		return present == *c.Present
+	}
+	if !present {
+		return false
+	}
+	candidates := c.In
+	if c.Equals != "" {
+		candidates = []string{c.Equals}
+	}
+	if len(candidates) == 0 {
+		return true
+	}
+	typ := ruleMap[c.Key].Type
+	for _, want := range candidates {
+		if valuesEqual(value, want, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// requirement is the outcome of evaluating a rule's conditional clauses.
+type requirement struct {
+	required    bool
+	requiredBy  string
+	forbiddenBy string
+}
+
+// evalRequirement resolves Required, RequiredIf, RequiredUnless and
+// ForbiddenIf for rule. requiredBy and forbiddenBy name the clause that applied.
+func evalRequirement(env EnvReader, rule Rule, ruleMap map[string]Rule) requirement {
+	req := requirement{required: rule.Required}
+	if !req.required {
+		for _, c := range rule.RequiredIf {
+			if c.holds(env, ruleMap) {
+				req.required = true
+				req.requiredBy = "required_if " + c.String()
+				break
+			}
+		}
+	}
+	if !req.required && len(rule.RequiredUnless) > 0 {
+		held := false
+		for _, c := range rule.RequiredUnless {
+			if c.holds(env, ruleMap) {
+				held = true
+				break
+			}
+		}
+		if !held {
+			req.required = true
+			req.requiredBy = "required_unless " + conditionList(rule.RequiredUnless)
+		}
+	}
+	for _, c := range rule.ForbiddenIf {
+		if c.holds(env, ruleMap) {
+			req.forbiddenBy = "forbidden_if " + c.String()
+			break
+		}
+	}
+	return req
+}
+
+// conditionList joins condition descriptions with "or".
+func conditionList(conds []Condition) string {
+	parts := make([]string, len(conds))
+	for i, c := range conds {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " or ")
+}
+
+// valuesEqual compares two raw values, parsing them as typ when typ is a
+// scalar type. Values that fail to parse are never equal.
+func valuesEqual(a, b string, typ VarType) bool {
+	switch typ {
+	case "", TypeString, TypeJSON, TypeList, TypeMap:
+		return a == b
+	}
+	pa, err := parseValue(a, typ)
+	if err != nil {
+		return false
+	}
+	pb, err := parseValue(b, typ)
+	if err != nil {
+		return false
+	}
+	return equalParsed(pa, pb)
+}
+
+// equalParsed compares two values returned by parseValue for the same type.
+func equalParsed(a, b any) bool {
+	if ua, ok := a.(*url.URL); ok {
+		return ua.String() == b.(*url.URL).String()
+	}
+	return a == b
+}
+
+// validateConditions checks the conditional clauses of every rule: each
+// condition needs a key other than its own, at most one test, and values
+// that parse as the referenced key's type.
+func validateConditions(rules []Rule) error {
+	ruleMap := keyedRules(rules)
+	for idx, r := range rules {
+		if r.Required && (len(r.RequiredIf) > 0 || len(r.RequiredUnless) > 0) {
+			return fmt.Errorf("envdoc: rule[%d] (%s): required cannot be combined with required_if or required_unless", idx, r.name())
+		}
+		clauses := []struct {
+			name  string
+			conds []Condition
+		}{
+			{"required_if", r.RequiredIf},
+			{"required_unless", r.RequiredUnless},
+			{"forbidden_if", r.ForbiddenIf},
+		}
+		for _, cl := range clauses {
+			for ci, c := range cl.conds {
+				if err := validateCondition(c, r.Key, ruleMap); err != nil {
+					return fmt.Errorf("envdoc: rule[%d] (%s): %s[%d]: %w", idx, r.name(), cl.name, ci, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateCondition(c Condition, self string, ruleMap map[string]Rule) error {
+	if c.Key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if c.Key == self {
+		return fmt.Errorf("condition cannot reference its own key")
+	}
+	tests := 0
+	if c.Present != nil {
+		tests++
+	}
+	if c.Equals != "" {
+		tests++
+	}
+	if len(c.In) > 0 {
+		tests++
+	}
+	if tests > 1 {
+		return fmt.Errorf("only one of present, equals and in may be set")
+	}
+	typ := ruleMap[c.Key].Type
+	switch typ {
+	case "", TypeString, TypeJSON, TypeList, TypeMap:
+		return nil
+	}
+	for _, v := range append([]string{c.Equals}, c.In...) {
+		if v == "" {
+			continue
+		}
+		if _, err := parseValue(v, typ); err != nil {
+			return fmt.Errorf("value %q for %s: %w", v, c.Key, err)
+		}
+	}
+	return nil
+}
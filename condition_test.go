@@ -0,0 +1,140 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCondition_Holds(t *testing.T) {
+	env := MapEnvReader{
+		"TLS_ENABLED":     "1",
+		"STORAGE_BACKEND": "s3",
+	}
+	ruleMap := map[string]Rule{
+		"TLS_ENABLED": {Key: "TLS_ENABLED", Type: TypeBool},
+	}
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"set", Condition{Key: "STORAGE_BACKEND"}, true},
+		{"unset", Condition{Key: "NOPE"}, false},
+		{"present false", Condition{Key: "NOPE", Present: boolPtr(false)}, true},
+		{"typed equals", Condition{Key: "TLS_ENABLED", Equals: "true"}, true},
+		{"raw equals", Condition{Key: "STORAGE_BACKEND", Equals: "gcs"}, false},
+		{"in", Condition{Key: "STORAGE_BACKEND", In: []string{"gcs", "s3"}}, true},
+		{"equals unset", Condition{Key: "NOPE", Equals: "x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.holds(env, ruleMap); got != tt.want {
+				t.Errorf("holds() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspect_RequiredIf(t *testing.T) {
+	rules := []Rule{
+		{Key: "TLS_ENABLED", Type: TypeBool},
+		{Key: "TLS_CERT_FILE", RequiredIf: []Condition{{Key: "TLS_ENABLED", Equals: "true"}}},
+	}
+	clock := fixedClock{t: time.Now()}
+
+	report := inspect(MapEnvReader{"TLS_ENABLED": "false"}, clock, RuleSet{Rules: rules}, Config{}, nil)
+	if r := report.Results[1]; r.Required || !r.Valid {
+		t.Errorf("expected TLS_CERT_FILE optional when TLS disabled: %+v", r)
+	}
+
+	report = inspect(MapEnvReader{"TLS_ENABLED": "true"}, clock, RuleSet{Rules: rules}, Config{}, nil)
+	r := report.Results[1]
+	if !r.Required || r.Valid {
+		t.Errorf("expected TLS_CERT_FILE required and invalid: %+v", r)
+	}
+	if r.RequiredBy != "required_if TLS_ENABLED == true" {
+		t.Errorf("unexpected required_by: %q", r.RequiredBy)
+	}
+	if report.Summary.Missing != 1 {
+		t.Errorf("expected 1 missing, got %d", report.Summary.Missing)
+	}
+
+	err := CheckFailFast(report, true)
+	if err == nil || !strings.Contains(err.Error(), "required_if TLS_ENABLED == true") {
+		t.Errorf("expected fail-fast error naming the condition, got %v", err)
+	}
+}
+
+func TestInspect_RequiredUnless(t *testing.T) {
+	rules := []Rule{
+		{Key: "DB_HOST", RequiredUnless: []Condition{{Key: "DATABASE_URL"}}},
+	}
+	clock := fixedClock{t: time.Now()}
+
+	report := inspect(MapEnvReader{"DATABASE_URL": "postgres://db"}, clock, RuleSet{Rules: rules}, Config{}, nil)
+	if report.Results[0].Required {
+		t.Error("expected DB_HOST optional when DATABASE_URL is set")
+	}
+
+	report = inspect(MapEnvReader{}, clock, RuleSet{Rules: rules}, Config{}, nil)
+	r := report.Results[0]
+	if !r.Required || r.RequiredBy != "required_unless DATABASE_URL is set" {
+		t.Errorf("expected DB_HOST required unless DATABASE_URL: %+v", r)
+	}
+}
+
+func TestInspect_ForbiddenIf(t *testing.T) {
+	rules := []Rule{
+		{Key: "S3_BUCKET", ForbiddenIf: []Condition{{Key: "STORAGE_BACKEND", In: []string{"local", "memory"}}}},
+	}
+	env := MapEnvReader{"STORAGE_BACKEND": "local", "S3_BUCKET": "my-bucket"}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	r := report.Results[0]
+	if r.Valid {
+		t.Error("expected S3_BUCKET invalid when forbidden")
+	}
+	if len(r.Problems) != 1 || r.Problems[0].Message != "set but forbidden_if STORAGE_BACKEND in [local, memory]" {
+		t.Errorf("unexpected problems: %v", r.Problems)
+	}
+	if err := CheckFailFast(report, true); err == nil {
+		t.Error("expected fail-fast error for forbidden variable")
+	}
+}
+
+func TestValidateRules_Conditions(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"valid", []Rule{
+			{Key: "TLS_ENABLED", Type: TypeBool},
+			{Key: "TLS_CERT_FILE", RequiredIf: []Condition{{Key: "TLS_ENABLED", Equals: "true"}}},
+		}, ""},
+		{"missing key", []Rule{{Key: "A", RequiredIf: []Condition{{Equals: "x"}}}}, "required_if[0]: key is required"},
+		{"self reference", []Rule{{Key: "A", ForbiddenIf: []Condition{{Key: "A"}}}}, "own key"},
+		{"two tests", []Rule{{Key: "A", RequiredIf: []Condition{{Key: "B", Equals: "x", In: []string{"y"}}}}}, "only one of"},
+		{"typed mismatch", []Rule{
+			{Key: "PORT", Type: TypeInt},
+			{Key: "A", RequiredIf: []Condition{{Key: "PORT", In: []string{"80", "http"}}}},
+		}, `value "http" for PORT: not a valid int`},
+		{"required and conditional", []Rule{{Key: "A", Required: true, RequiredIf: []Condition{{Key: "B"}}}}, "cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules(tt.rules)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -19,14 +19,17 @@ type Config struct {
 	Mode               Mode
 	EnableHTTP         bool
 	FailFast           bool
+	FailOn             Severity
 	DumpAll            bool
 	DumpAllFingerprint bool
 	Token              string
 	ExpiresAt          time.Time
 	ListenAddr         string
+	Profile            string
 }
 
 // LoadConfig reads ENVDOC_* environment variables from the given EnvReader.
+// A valid ENVDOC_FAIL_ON severity also enables fail-fast.
 func LoadConfig(env EnvReader) Config {
 	cfg := Config{
 		Mode: ModeAllowlist,
@@ -39,9 +42,16 @@ func LoadConfig(env EnvReader) Config {
 
 	cfg.EnableHTTP = parseBool(env.Getenv("ENVDOC_ENABLE_HTTP"))
 	cfg.FailFast = parseBool(env.Getenv("ENVDOC_FAIL_FAST"))
+	if s := env.Getenv("ENVDOC_FAIL_ON"); s != "" {
+		if sev, err := ParseSeverity(s); err == nil {
+			cfg.FailOn = sev
+			cfg.FailFast = true
+		}
+	}
 	cfg.DumpAllFingerprint = parseBool(env.Getenv("ENVDOC_DUMP_ALL_FINGERPRINT"))
 	cfg.Token = env.Getenv("ENVDOC_TOKEN")
 	cfg.ListenAddr = env.Getenv("ENVDOC_LISTEN_ADDR")
+	cfg.Profile = env.Getenv("ENVDOC_PROFILE")
 
 	if exp := env.Getenv("ENVDOC_EXPIRES_AT"); exp != "" {
 		if t, err := time.Parse(time.RFC3339, exp); err == nil {
@@ -52,6 +62,14 @@ func LoadConfig(env EnvReader) Config {
 	return cfg
 }
 
+// failOn returns the fail-fast severity threshold, defaulting to error.
+func (c Config) failOn() Severity {
+	if c.FailOn == "" {
+		return SeverityError
+	}
+	return c.FailOn
+}
+
 func parseBool(s string) bool {
 	return strings.EqualFold(s, "true") || s == "1"
 }
@@ -53,6 +53,8 @@ func TestLoadConfig_AllOptions(t *testing.T) {
 		"ENVDOC_TOKEN":                "my-secret",
 		"ENVDOC_EXPIRES_AT":           "2026-02-05T20:00:00Z",
 		"ENVDOC_LISTEN_ADDR":          "0.0.0.0:8080",
+		"ENVDOC_PROFILE":              "prod",
+		"ENVDOC_FAIL_ON":              "Warning",
 	}
 	cfg := LoadConfig(env)
 
@@ -81,4 +83,20 @@ func TestLoadConfig_AllOptions(t *testing.T) {
 	if cfg.ListenAddr != "0.0.0.0:8080" {
 		t.Errorf("expected 0.0.0.0:8080, got %q", cfg.ListenAddr)
 	}
+	if cfg.Profile != "prod" {
+		t.Errorf("expected profile 'prod', got %q", cfg.Profile)
+	}
+	if cfg.FailOn != SeverityWarning {
+		t.Errorf("expected fail-on warning, got %q", cfg.FailOn)
+	}
+}
+
+func TestLoadConfig_FailOnEnablesFailFast(t *testing.T) {
+	cfg := LoadConfig(MapEnvReader{"ENVDOC_FAIL_ON": "info"})
+	if !cfg.FailFast || cfg.FailOn != SeverityInfo {
+		t.Errorf("expected fail-fast at info, got %+v", cfg)
+	}
+	if cfg := LoadConfig(MapEnvReader{"ENVDOC_FAIL_ON": "sometimes"}); cfg.FailFast || cfg.FailOn != "" {
+		t.Errorf("expected invalid ENVDOC_FAIL_ON to be ignored, got %+v", cfg)
+	}
 }
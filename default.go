@@ -0,0 +1,51 @@
+package envdoc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lookup returns the effective value of key: its value from the Inspector's
+// EnvReader when set, or else the default of its rule. ok is false when the
+// variable is unset and has no default.
+func (i *Inspector) Lookup(key string) (value string, ok bool) {
+	if value, ok := i.env.LookupEnv(key); ok {
+		return value, true
+	}
+	for _, r := range i.ruleSet.Rules {
+		if r.Key == key && r.Default != nil {
+			return *r.Default, true
+		}
+	}
+	return "", false
+}
+
+// checkDefault marks an unset variable whose rule has a default.
+func checkDefault(vr *VarResult, rule Rule) {
+	if !vr.Present && rule.Default != nil {
+		vr.Defaulted = true
+	}
+}
+
+// validateDefault checks that a default is set only where it can apply and
+// that it satisfies the rule's own type and constraints. Problems of any
+// severity reject it, so an unset variable never falls back to a value the
+// rule would flag.
+func validateDefault(r Rule) error {
+	if r.Default == nil {
+		return nil
+	}
+	if r.isPattern() {
+		return fmt.Errorf("default is not supported on pattern rules")
+	}
+	if r.Required {
+		return fmt.Errorf("default cannot be combined with required")
+	}
+	if detectWhitespace(*r.Default) {
+		return fmt.Errorf("invalid default: leading or trailing whitespace")
+	}
+	if problems := checkVar(*r.Default, r, nil); len(problems) > 0 {
+		return fmt.Errorf("invalid default: %s", strings.Join(problemMessages(problems), "; "))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package envdoc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const defaultYAML = `
+rules:
+  - key: LOG_LEVEL
+    default: info
+    allowed: [debug, info, warn]
+  - key: PORT
+    type: port
+    default: 8080
+  - key: DB_HOST
+    required: true
+`
+
+func TestInspect_Defaulted(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(defaultYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rs.Rules[1].Default != "8080" {
+		t.Fatalf("expected scalar default as string, got %q", *rs.Rules[1].Default)
+	}
+
+	var out bytes.Buffer
+	report, err := Run(WithEnvReader(MapEnvReader{"PORT": "9090"}), WithRuleSet(rs), WithOutput(&out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logLevel, port, dbHost := report.Results[0], report.Results[1], report.Results[2]
+	if !logLevel.Defaulted || !logLevel.Valid || len(logLevel.Problems) != 0 {
+		t.Errorf("expected LOG_LEVEL defaulted and valid, got %+v", logLevel)
+	}
+	if port.Defaulted || dbHost.Defaulted {
+		t.Errorf("expected only unset vars with a default to be defaulted, got %+v %+v", port, dbHost)
+	}
+	if report.Summary.Defaulted != 1 || report.Summary.Missing != 1 {
+		t.Errorf("unexpected summary %+v", report.Summary)
+	}
+	if !strings.Contains(out.String(), "envdoc: key=LOG_LEVEL present=false defaulted=true valid=true\n") {
+		t.Errorf("expected defaulted in log, got %s", out.String())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(defaultYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := New(WithEnvReader(MapEnvReader{"PORT": "9090", "EXTRA": ""}), WithRuleSet(rs))
+
+	tests := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{"PORT", "9090", true},
+		{"LOG_LEVEL", "info", true},
+		{"EXTRA", "", true},
+		{"DB_HOST", "", false},
+		{"UNKNOWN", "", false},
+	}
+	for _, tt := range tests {
+		if value, ok := i.Lookup(tt.key); value != tt.value || ok != tt.ok {
+			t.Errorf("Lookup(%s) = %q, %t; want %q, %t", tt.key, value, ok, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestValidateRules_Default(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"wrong type", "key: PORT\n    type: int\n    default: eighty", "invalid default: not a valid int"},
+		{"out of range", "key: TIMEOUT\n    type: duration\n    max: 1m\n    default: 5m", "invalid default: value above maximum 1m"},
+		{"not allowed", "key: MODE\n    allowed: [a, b]\n    default: c", "invalid default: value not in allowed set [a, b]"},
+		{"bad item", "key: PORTS\n    type: list\n    item_type: port\n    default: 80,x", "invalid default: item[1]: port: not a number"},
+		{"whitespace", "key: MODE\n    default: ' a'", "invalid default: leading or trailing whitespace"},
+		{"required", "key: MODE\n    required: true\n    default: a", "default cannot be combined with required"},
+		{"pattern", "key_pattern: FEATURE_*\n    default: a", "default is not supported on pattern rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRules([]byte("rules:\n  - " + tt.rule + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRulesFromStruct_Default(t *testing.T) {
+	var cfg struct {
+		Timeout time.Duration `env:"TIMEOUT" envDefault:"30s" envdoc:"max=1m"`
+		Retries int           `env:"RETRIES" envDefault:"many"`
+	}
+	if _, err := RulesFromStruct(&cfg); err == nil || !strings.Contains(err.Error(), "rule[1] (RETRIES): invalid default: not a valid int") {
+		t.Fatalf("expected invalid default error, got %v", err)
+	}
+
+	var ok struct {
+		Timeout time.Duration `env:"TIMEOUT" envDefault:"30s" envdoc:"max=1m"`
+	}
+	rules, err := RulesFromStruct(&ok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Default == nil || *rules[0].Default != "30s" {
+		t.Errorf("expected default from envDefault, got %+v", rules[0])
+	}
+}
@@ -0,0 +1,89 @@
+package envdoc
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the layout of removal_date and fail_after when given as a
+// plain date; full RFC 3339 timestamps are accepted as well.
+const dateLayout = "2006-01-02"
+
+// parseDate parses a date (midnight UTC) or RFC 3339 timestamp.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// deprecationWarning describes a deprecated variable by name, replacement
+// and removal date.
+func deprecationWarning(rule Rule) string {
+	msg := "deprecated"
+	if rule.ReplacedBy != "" {
+		msg += ", use " + rule.ReplacedBy + " instead"
+	}
+	if rule.RemovalDate != "" {
+		msg += ", removal on " + rule.RemovalDate
+	}
+	return msg
+}
+
+// checkDeprecated records problems for a set, deprecated variable: a
+// deprecation warning always, a conflict when the replacement is also set
+// with a different value, and an expiry once now is past the rule's
+// fail_after date. Values are compared by fingerprint only.
+func checkDeprecated(env EnvReader, vr *VarResult, rule Rule, now time.Time) {
+	if !rule.Deprecated || !vr.Present {
+		return
+	}
+	vr.Deprecated = true
+	vr.ReplacedBy = rule.ReplacedBy
+	vr.addProblem(rule, newProblem(CodeDeprecated, "deprecated", true, "%s", deprecationWarning(rule)))
+
+	if rule.ReplacedBy != "" {
+		value, _ := env.LookupEnv(vr.Key)
+		if other, ok := env.LookupEnv(rule.ReplacedBy); ok && FingerprintValue(other) != FingerprintValue(value) {
+			vr.addProblem(rule, newProblem(CodeConflict, "replaced_by", rule.ReplacedBy, "conflicts with %s: both set with different values", rule.ReplacedBy))
+		}
+	}
+
+	if rule.FailAfter != "" {
+		if t, err := parseDate(rule.FailAfter); err == nil && now.After(t) {
+			vr.Expired = true
+			vr.addProblem(rule, newProblem(CodeExpired, "fail_after", rule.FailAfter, "deprecated and past fail_after %s", rule.FailAfter))
+		}
+	}
+}
+
+// validateDeprecation checks that replacement and date fields are only used
+// on deprecated rules and that dates parse.
+func validateDeprecation(r Rule) error {
+	if !r.Deprecated {
+		if r.ReplacedBy != "" || r.RemovalDate != "" || r.FailAfter != "" {
+			return fmt.Errorf("replaced_by, removal_date and fail_after require deprecated: true")
+		}
+		return nil
+	}
+	if r.ReplacedBy != "" {
+		if r.isPattern() {
+			return fmt.Errorf("replaced_by is not supported on pattern rules")
+		}
+		if r.ReplacedBy == r.Key {
+			return fmt.Errorf("replaced_by cannot reference its own key")
+		}
+	}
+	for _, f := range []struct{ name, value string }{
+		{"removal_date", r.RemovalDate},
+		{"fail_after", r.FailAfter},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if _, err := parseDate(f.value); err != nil {
+			return fmt.Errorf("invalid %s %q: want YYYY-MM-DD or RFC 3339", f.name, f.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func deprecatedRules() []Rule {
+	return []Rule{
+		{Key: "REDIS_ADDR", Deprecated: true, ReplacedBy: "CACHE_ADDR", RemovalDate: "2026-09-01", FailAfter: "2026-06-01"},
+		{Key: "CACHE_ADDR", Type: TypeHostPort},
+	}
+}
+
+func TestInspect_Deprecated(t *testing.T) {
+	before := fixedClock{t: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	after := fixedClock{t: time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name    string
+		env     MapEnvReader
+		clock   Clock
+		valid   bool
+		expired bool
+		problem string
+	}{
+		{"old name only", MapEnvReader{"REDIS_ADDR": "cache:6379"}, before, true, false, ""},
+		{"both same value", MapEnvReader{"REDIS_ADDR": "cache:6379", "CACHE_ADDR": "cache:6379"}, before, true, false, ""},
+		{"both different values", MapEnvReader{"REDIS_ADDR": "old:6379", "CACHE_ADDR": "new:6379"}, before, false, false, "conflicts with CACHE_ADDR"},
+		{"past fail_after", MapEnvReader{"REDIS_ADDR": "cache:6379"}, after, false, true, "past fail_after 2026-06-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := inspect(tt.env, tt.clock, RuleSet{Rules: deprecatedRules()}, Config{}, nil)
+			r := report.Results[0]
+			if !r.Deprecated || r.ReplacedBy != "CACHE_ADDR" {
+				t.Errorf("expected deprecated result, got %+v", r)
+			}
+			warning := Problem{Code: CodeDeprecated, Field: "deprecated", Severity: SeverityWarning, Expected: true, Message: "deprecated, use CACHE_ADDR instead, removal on 2026-09-01"}
+			if len(r.Problems) == 0 || r.Problems[0] != warning {
+				t.Errorf("expected deprecation warning first, got %v", r.Problems)
+			}
+			if r.Valid != tt.valid || r.Expired != tt.expired {
+				t.Errorf("expected valid=%t expired=%t, got %+v", tt.valid, tt.expired, r)
+			}
+			if tt.problem != "" && (len(r.Problems) != 2 || !strings.Contains(r.Problems[1].Message, tt.problem)) {
+				t.Errorf("expected problem %q, got %v", tt.problem, r.Problems)
+			}
+			for _, p := range r.Problems {
+				if strings.Contains(p.Message, "6379") {
+					t.Errorf("problem leaks value: %q", p)
+				}
+			}
+		})
+	}
+}
+
+func TestInspect_DeprecatedUnset(t *testing.T) {
+	clock := fixedClock{t: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}
+	report := inspect(MapEnvReader{"CACHE_ADDR": "cache:6379"}, clock, RuleSet{Rules: deprecatedRules()}, Config{}, nil)
+	if r := report.Results[0]; r.Deprecated || len(r.Problems) > 0 || !r.Valid {
+		t.Errorf("expected no warning for unset deprecated var, got %+v", r)
+	}
+}
+
+func TestCheckFailFast_Deprecated(t *testing.T) {
+	env := MapEnvReader{"REDIS_ADDR": "old:6379"}
+	rs := RuleSet{Rules: deprecatedRules()}
+
+	before := inspect(env, fixedClock{t: time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)}, rs, Config{}, nil)
+	if err := CheckFailFast(before, true); err != nil {
+		t.Errorf("expected no fail-fast during grace period, got %v", err)
+	}
+
+	after := inspect(env, fixedClock{t: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)}, rs, Config{}, nil)
+	err := CheckFailFast(after, true)
+	if err == nil || !strings.Contains(err.Error(), "REDIS_ADDR") {
+		t.Fatalf("expected fail-fast naming REDIS_ADDR, got %v", err)
+	}
+}
+
+func TestValidateRules_Deprecation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"replaced_by without deprecated", Rule{Key: "A", ReplacedBy: "B"}, "require deprecated: true"},
+		{"self replacement", Rule{Key: "A", Deprecated: true, ReplacedBy: "A"}, "its own key"},
+		{"bad removal_date", Rule{Key: "A", Deprecated: true, RemovalDate: "next year"}, `invalid removal_date "next year"`},
+		{"bad fail_after", Rule{Key: "A", Deprecated: true, FailAfter: "2026-13-01"}, "invalid fail_after"},
+		{"pattern replacement", Rule{KeyPattern: "OLD_*", Deprecated: true, ReplacedBy: "NEW"}, "pattern rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules([]Rule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	ok := Rule{Key: "A", Deprecated: true, ReplacedBy: "B", RemovalDate: "2026-09-01", FailAfter: "2026-06-01T00:00:00Z"}
+	if err := validateRules([]Rule{ok}); err != nil {
+		t.Errorf("expected valid rule, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package envdoc
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// validateDocs checks that docs_url is an absolute http(s) URL and that the
+// example is a value the rule accepts.
+func validateDocs(r Rule) error {
+	if r.DocsURL != "" {
+		u, err := url.Parse(r.DocsURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("docs_url must be an absolute http or https URL")
+		}
+	}
+	if r.Example == "" {
+		return nil
+	}
+	if detectWhitespace(r.Example) {
+		return fmt.Errorf("invalid example: leading or trailing whitespace")
+	}
+	if problems := checkVar(r.Example, r, nil); len(problems) > 0 {
+		return fmt.Errorf("invalid example: %s", strings.Join(problemMessages(problems), "; "))
+	}
+	return nil
+}
+
+// docSection is a titled run of rules in generated documentation.
+type docSection struct {
+	title string
+	rules []Rule
+}
+
+// docSections groups rules by their group, or else by the part of the key
+// or key pattern before the first underscore. Sections keep the order in
+// which they first appear, and rules keep their order within a section.
+func docSections(rules []Rule) []docSection {
+	var sections []docSection
+	index := make(map[string]int)
+	for _, r := range rules {
+		title := r.Group
+		if title == "" {
+			title = "Other"
+			if prefix, _, ok := strings.Cut(r.Key+r.KeyPattern, "_"); ok && prefix != "" {
+				title = prefix
+			}
+		}
+		i, ok := index[title]
+		if !ok {
+			i = len(sections)
+			index[title] = i
+			sections = append(sections, docSection{title: title})
+		}
+		sections[i].rules = append(sections[i].rules, r)
+	}
+	return sections
+}
+
+// docType describes the rule's type, with the item type of lists and maps.
+func docType(r Rule) string {
+	typ := r.Type
+	if typ == "" {
+		typ = TypeString
+	}
+	if r.isCollection() {
+		item := r.ItemType
+		if item == "" {
+			item = TypeString
+		}
+		return fmt.Sprintf("%s of %s", typ, item)
+	}
+	return string(typ)
+}
+
+// docRequirement describes when the variable is required.
+func docRequirement(r Rule) string {
+	switch {
+	case r.Required:
+		return "required"
+	case len(r.RequiredIf) > 0:
+		return "required if " + conditionList(r.RequiredIf)
+	case len(r.RequiredUnless) > 0:
+		return "required unless " + conditionList(r.RequiredUnless)
+	}
+	return "optional"
+}
+
+// docConstraints describes the rule's checks, with code formatting rule
+// values such as regexes. The allowed values and regex of secret-like
+// variables would give the secret away, so only their presence is noted.
+func docConstraints(r Rule, code func(string) string) []string {
+	secret := classifySecretLike(r.name(), r)
+	var out []string
+	if r.Min != "" {
+		op := ">="
+		if r.ExclusiveMin {
+			op = ">"
+		}
+		out = append(out, op+" "+code(r.Min))
+	}
+	if r.Max != "" {
+		op := "<="
+		if r.ExclusiveMax {
+			op = "<"
+		}
+		out = append(out, op+" "+code(r.Max))
+	}
+	if r.MinLen != nil {
+		out = append(out, fmt.Sprintf("length >= %d", *r.MinLen))
+	}
+	if r.MaxLen != nil {
+		out = append(out, fmt.Sprintf("length <= %d", *r.MaxLen))
+	}
+	if r.IPVersion != 0 {
+		out = append(out, fmt.Sprintf("IPv%d", r.IPVersion))
+	}
+	switch {
+	case r.Regex == "":
+	case secret:
+		out = append(out, "matches a regex")
+	default:
+		out = append(out, "matches "+code(r.Regex))
+	}
+	switch {
+	case len(r.Allowed) == 0:
+	case secret:
+		out = append(out, fmt.Sprintf("one of %d allowed values", len(r.Allowed)))
+	default:
+		vals := make([]string, len(r.Allowed))
+		for i, a := range r.Allowed {
+			vals[i] = code(a)
+		}
+		out = append(out, "one of "+strings.Join(vals, ", "))
+	}
+	if r.isCollection() && r.Separator != "" {
+		out = append(out, "separated by "+code(r.Separator))
+	}
+	if r.MinItems != nil {
+		out = append(out, fmt.Sprintf("at least %d items", *r.MinItems))
+	}
+	if r.MaxItems != nil {
+		out = append(out, fmt.Sprintf("at most %d items", *r.MaxItems))
+	}
+	if r.Unique {
+		out = append(out, "unique items")
+	}
+	if len(r.RequiredKeys) > 0 {
+		out = append(out, "keys include "+strings.Join(r.RequiredKeys, ", "))
+	}
+	if len(r.AllowedKeys) > 0 {
+		out = append(out, "keys from "+strings.Join(r.AllowedKeys, ", "))
+	}
+	if r.JSONSchema != nil {
+		out = append(out, "JSON Schema")
+	}
+	for _, c := range r.Compare {
+		out = append(out, c.describe(r.name()))
+	}
+	if len(r.ForbiddenIf) > 0 {
+		out = append(out, "forbidden if "+conditionList(r.ForbiddenIf))
+	}
+	if r.Expr != "" {
+		out = append(out, code(r.Expr))
+	}
+	return out
+}
+
+// docDescription joins the rule's deprecation notice, description and owner.
+func docDescription(r Rule) string {
+	var parts []string
+	if r.Deprecated {
+		notice := "Deprecated"
+		if r.ReplacedBy != "" {
+			notice += ", use " + r.ReplacedBy + " instead"
+		}
+		parts = append(parts, notice+".")
+	}
+	if r.Description != "" {
+		parts = append(parts, r.Description)
+	}
+	if r.Owner != "" {
+		parts = append(parts, "Owner: "+r.Owner+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// mdCode formats s as Markdown inline code, fenced with enough backticks
+// that s may contain them.
+func mdCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// mdCell escapes s for a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// WriteMarkdown writes Markdown documentation for rules to w: a section per
+// group (see docSections) with a table row per rule. Defaults and examples
+// of secret-like variables are never shown.
+func WriteMarkdown(w io.Writer, rules []Rule) error {
+	var b strings.Builder
+	for i, s := range docSections(rules) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", s.title)
+		b.WriteString("| Variable | Type | Required | Default | Example | Constraints | Description |\n")
+		b.WriteString("|----------|------|----------|---------|---------|-------------|-------------|\n")
+		for _, r := range s.rules {
+			name := mdCode(r.name())
+			if r.DocsURL != "" {
+				name = "[" + name + "](" + r.DocsURL + ")"
+			}
+			var def, example string
+			secret := classifySecretLike(r.name(), r)
+			switch {
+			case r.Default == nil:
+			case secret:
+				def = "*secret*"
+			default:
+				def = mdCode(*r.Default)
+			}
+			switch {
+			case secret:
+				example = "*secret*"
+			case r.Example != "":
+				example = mdCode(r.Example)
+			}
+			cells := []string{
+				name, docType(r), docRequirement(r), def, example,
+				strings.Join(docConstraints(r, mdCode), "; "), docDescription(r),
+			}
+			for i, c := range cells {
+				cells[i] = mdCell(c)
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteEnvExample writes a commented .env.example for rules to w. Each
+// variable gets its description and a summary of its type and constraints,
+// then an assignment of its example or default. Assignments of optional
+// variables are commented out. Secret-like variables are assigned the
+// placeholder <secret> and their defaults are not shown; deprecated
+// variables are left out, and pattern rules are only described.
+func WriteEnvExample(w io.Writer, rules []Rule) error {
+	var current []Rule
+	for _, r := range rules {
+		if !r.Deprecated {
+			current = append(current, r)
+		}
+	}
+	plain := func(s string) string { return s }
+	var b strings.Builder
+	for i, s := range docSections(current) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# --- %s ---\n", s.title)
+		for _, r := range s.rules {
+			b.WriteString("\n")
+			if r.isPattern() {
+				fmt.Fprintf(&b, "# Variables matching %s\n", r.pattern())
+			}
+			if d := docDescription(r); d != "" {
+				fmt.Fprintf(&b, "# %s\n", strings.Join(strings.Fields(d), " "))
+			}
+			secret := classifySecretLike(r.name(), r)
+			summary := []string{docType(r), docRequirement(r)}
+			if secret {
+				summary = append(summary, "secret")
+			}
+			summary = append(summary, docConstraints(r, plain)...)
+			if r.Default != nil && !secret {
+				summary = append(summary, "default "+*r.Default)
+			}
+			if r.DocsURL != "" {
+				summary = append(summary, "see "+r.DocsURL)
+			}
+			fmt.Fprintf(&b, "# %s\n", strings.Join(summary, "; "))
+
+			if r.isPattern() {
+				continue
+			}
+			var value string
+			switch {
+			case secret:
+				value = secretPlaceholder
+			case r.Example != "":
+				value = r.Example
+			case r.Default != nil:
+				value = *r.Default
+			}
+			prefix := ""
+			if !r.Required {
+				prefix = "# "
+			}
+			fmt.Fprintf(&b, "%s%s=%s\n", prefix, r.Key, envQuote(value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// secretPlaceholder is the value .env.example assigns to secret-like
+// variables, so the file shows they need one without suggesting it.
+const secretPlaceholder = "<secret>"
+
+// envQuote quotes a .env value when it contains characters that would
+// otherwise end or change it: in single quotes if it has none, else in
+// double quotes with backslash escapes.
+func envQuote(v string) string {
+	if !strings.ContainsAny(v, " \t#\"'\\$`") {
+		return v
+	}
+	if !strings.Contains(v, "'") {
+		return "'" + v + "'"
+	}
+	return strconv.Quote(v)
+}
@@ -0,0 +1,196 @@
+package envdoc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const docsYAML = `
+rules:
+  - key: DB_HOST
+    required: true
+    description: Database host name.
+    example: db.internal
+    owner: platform
+    docs_url: https://example.com/db#host
+  - key: DB_PASSWORD
+    required: true
+    min_len: 16
+    example: correct-horse-battery
+  - key: DB_TOKEN
+    default: dev-token-not-for-prod
+  - key: DB_API_KEY
+    regex: "^key-[a-z]+$"
+    allowed: [key-alpha, key-beta]
+  - key: DB_POOL
+    type: int
+    min: 1
+    max: 100
+    default: "10"
+  - key: LOG_LEVEL
+    group: Logging
+    allowed: [debug, info]
+    example: debug
+  - key: OLD_HOST
+    deprecated: true
+    replaced_by: DB_HOST
+  - key_pattern: FEATURE_*
+    type: bool
+    description: Feature toggles.
+`
+
+func TestWriteMarkdown(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(docsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteMarkdown(&out, rs.Rules); err != nil {
+		t.Fatal(err)
+	}
+	want := "## DB\n\n" +
+		"| Variable | Type | Required | Default | Example | Constraints | Description |\n" +
+		"|----------|------|----------|---------|---------|-------------|-------------|\n" +
+		"| [`DB_HOST`](https://example.com/db#host) | string | required |  | `db.internal` |  | Database host name. Owner: platform. |\n" +
+		"| `DB_PASSWORD` | string | required |  | *secret* | length >= 16 |  |\n" +
+		"| `DB_TOKEN` | string | optional | *secret* | *secret* |  |  |\n" +
+		"| `DB_API_KEY` | string | optional |  | *secret* | matches a regex; one of 2 allowed values |  |\n" +
+		"| `DB_POOL` | int | optional | `10` |  | >= `1`; <= `100` |  |\n" +
+		"\n## Logging\n\n" +
+		"| Variable | Type | Required | Default | Example | Constraints | Description |\n" +
+		"|----------|------|----------|---------|---------|-------------|-------------|\n" +
+		"| `LOG_LEVEL` | string | optional |  | `debug` | one of `debug`, `info` |  |\n" +
+		"\n## OLD\n\n" +
+		"| Variable | Type | Required | Default | Example | Constraints | Description |\n" +
+		"|----------|------|----------|---------|---------|-------------|-------------|\n" +
+		"| `OLD_HOST` | string | optional |  |  |  | Deprecated, use DB_HOST instead. |\n" +
+		"\n## FEATURE\n\n" +
+		"| Variable | Type | Required | Default | Example | Constraints | Description |\n" +
+		"|----------|------|----------|---------|---------|-------------|-------------|\n" +
+		"| `FEATURE_*` | bool | optional |  |  |  | Feature toggles. |\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if strings.Contains(out.String(), "dev-token") || strings.Contains(out.String(), "key-") {
+		t.Error("secret default or allowed value leaked into Markdown")
+	}
+}
+
+func TestWriteEnvExample(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(docsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteEnvExample(&out, rs.Rules); err != nil {
+		t.Fatal(err)
+	}
+	want := `# --- DB ---
+
+# Database host name. Owner: platform.
+# string; required; see https://example.com/db#host
+DB_HOST=db.internal
+
+# string; required; secret; length >= 16
+DB_PASSWORD=<secret>
+
+# string; optional; secret
+# DB_TOKEN=<secret>
+
+# string; optional; secret; matches a regex; one of 2 allowed values
+# DB_API_KEY=<secret>
+
+# int; optional; >= 1; <= 100; default 10
+# DB_POOL=10
+
+# --- Logging ---
+
+# string; optional; one of debug, info
+# LOG_LEVEL=debug
+
+# --- FEATURE ---
+
+# Variables matching FEATURE_*
+# Feature toggles.
+# bool; optional
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if strings.Contains(out.String(), "correct-horse") || strings.Contains(out.String(), "dev-token") || strings.Contains(out.String(), "key-") {
+		t.Error("secret example, default or allowed value leaked into .env.example")
+	}
+}
+
+func TestEnvQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"plain", "plain"},
+		{"a b", "'a b'"},
+		{"a#b", "'a#b'"},
+		{`it's "x"`, `"it's \"x\""`},
+	}
+	for _, tt := range tests {
+		if got := envQuote(tt.in); got != tt.want {
+			t.Errorf("envQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRules_Docs(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"relative docs_url", "key: A\n    docs_url: /wiki/a", "docs_url must be an absolute http or https URL"},
+		{"docs_url scheme", "key: A\n    docs_url: ftp://example.com/a", "docs_url must be an absolute http or https URL"},
+		{"example type", "key: PORT\n    type: port\n    example: http", "invalid example: port: not a number"},
+		{"example allowed", "key: MODE\n    allowed: [a, b]\n    example: c", "invalid example: value not in allowed set [a, b]"},
+		{"example whitespace", "key: MODE\n    example: ' a'", "invalid example: leading or trailing whitespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRules([]byte("rules:\n  - " + tt.rule + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestInspect_ProblemDocsURL(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(`
+rules:
+  - key: PORT
+    type: port
+    docs_url: https://example.com/port
+  - key_pattern: FEATURE_*
+    min_matches: 1
+    docs_url: https://example.com/features
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	report, err := Run(WithEnvReader(MapEnvReader{"PORT": "http"}), WithRuleSet(rs), WithOutput(&log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := report.Results[0].Problems; len(p) != 1 || p[0].DocsURL != "https://example.com/port" {
+		t.Errorf("expected docs_url on var problem, got %+v", p)
+	}
+	if p := report.Patterns[0].Problems; len(p) != 1 || p[0].DocsURL != "https://example.com/features" {
+		t.Errorf("expected docs_url on pattern problem, got %+v", p)
+	}
+
+	// Operators see the link where they see the problem.
+	if !strings.Contains(log.String(), `(see https://example.com/port)"`) {
+		t.Errorf("expected docs_url in the log line, got:\n%s", log.String())
+	}
+	err = CheckFailOn(report, SeverityError)
+	if err == nil || !strings.Contains(err.Error(), "(see https://example.com/port)") || !strings.Contains(err.Error(), "(see https://example.com/features)") {
+		t.Errorf("expected docs_url in the fail-fast error, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,6 +29,10 @@ func (osEnvReader) Getenv(key string) string           { return os.Getenv(key) }
 func (osEnvReader) LookupEnv(key string) (string, bool) { return os.LookupEnv(key) }
 func (osEnvReader) Environ() []string                   { return os.Environ() }
 
+// OSEnv returns the EnvReader backed by the process environment, which New
+// uses by default.
+func OSEnv() EnvReader { return osEnvReader{} }
+
 // realClock implements Clock using real time.
 type realClock struct{}
 
@@ -35,11 +40,19 @@ func (realClock) Now() time.Time { return time.Now() }
 
 // Inspector is the main entry point for environment variable inspection.
 type Inspector struct {
-	env    EnvReader
-	clock  Clock
-	rules  []Rule
-	config Config
-	output io.Writer
+	env     EnvReader
+	clock   Clock
+	ruleSet RuleSet
+	config  Config
+	output  io.Writer
+	types   typeSet
+	// err is a setup error found by New, such as an unknown profile; Run
+	// and Bind return it.
+	err error
+
+	// mu guards bindFailures, which Bind records for later reports.
+	mu           sync.Mutex
+	bindFailures []bindFailure
 }
 
 // Option configures an Inspector.
@@ -57,7 +70,20 @@ func WithClock(c Clock) Option {
 
 // WithRules sets the validation rules.
 func WithRules(rules []Rule) Option {
-	return func(i *Inspector) { i.rules = rules }
+	return func(i *Inspector) { i.ruleSet.Rules = rules }
+}
+
+// WithRuleSet sets the validation rules and groups from a loaded RuleSet.
+// New applies the configured profile (ENVDOC_PROFILE) unless the rule set
+// already has one applied. A nil rs means no rules, like WithRules(nil).
+func WithRuleSet(rs *RuleSet) Option {
+	return func(i *Inspector) {
+		if rs == nil {
+			i.ruleSet = RuleSet{}
+			return
+		}
+		i.ruleSet = *rs
+	}
 }
 
 // WithConfig sets the configuration directly.
@@ -70,7 +96,23 @@ func WithOutput(w io.Writer) Option {
 	return func(i *Inspector) { i.output = w }
 }
 
-// New creates a new Inspector with the given options.
+// WithType sets the validator for a custom type for this Inspector only,
+// taking precedence over RegisterType. Rules files are checked against
+// registered types when loaded, so use it with rules built in Go or to
+// override a registered validator. It panics like RegisterType.
+func WithType(name VarType, fn TypeValidator) Option {
+	mustCustomType(name, fn)
+	return func(i *Inspector) {
+		if i.types == nil {
+			i.types = make(typeSet)
+		}
+		i.types[name] = fn
+	}
+}
+
+// New creates a new Inspector with the given options. If the config names a
+// profile and the rules have none applied, New applies it; an unknown
+// profile is returned by Run and Bind.
 func New(opts ...Option) *Inspector {
 	i := &Inspector{
 		env:    osEnvReader{},
@@ -84,8 +126,14 @@ func New(opts ...Option) *Inspector {
 	if i.config == (Config{}) {
 		i.config = LoadConfig(i.env)
 	}
+	hasRules := len(i.ruleSet.Rules) > 0 || len(i.ruleSet.Groups) > 0 || len(i.ruleSet.Assertions) > 0
+	// Apply the configured profile to a copy, leaving the caller's rule set
+	// as it was.
+	if hasRules && i.config.Profile != "" && i.ruleSet.ActiveProfile == "" {
+		i.err = i.ruleSet.ApplyProfile(i.config.Profile)
+	}
 	// No rules provided: default to dump-all metadata mode.
-	if len(i.rules) == 0 && !i.config.DumpAll {
+	if !hasRules && !i.config.DumpAll {
 		i.config.DumpAll = true
 		i.config.Mode = ModeDumpAll
 	}
@@ -99,22 +147,33 @@ func Run(opts ...Option) (*Report, error) {
 	return i.Run()
 }
 
-// Run performs the full inspection lifecycle.
+// Run performs the full inspection lifecycle. It returns New's setup error,
+// such as an unknown profile, without inspecting.
 func (i *Inspector) Run() (*Report, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
 	report := i.Inspect()
 
 	LogReport(i.output, report)
 
-	if err := CheckFailFast(report, i.config.FailFast); err != nil {
-		return report, err
+	if i.config.FailFast {
+		if err := CheckFailOn(report, i.config.failOn()); err != nil {
+			return report, err
+		}
 	}
 
 	return report, nil
 }
 
-// Inspect performs environment inspection and returns a Report.
+// Inspect performs environment inspection and returns a Report, including
+// any failures from the last Bind.
 func (i *Inspector) Inspect() *Report {
-	return inspect(i.env, i.clock, i.rules, i.config)
+	report := inspect(i.env, i.clock, i.ruleSet, i.config, i.types)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	addBindFailures(report, i.bindFailures)
+	return report
 }
 
 // Handler returns an http.Handler for the GET /debug/env endpoint.
@@ -140,24 +199,80 @@ func (i *Inspector) ListenAndServe(addr string) error {
 	return http.ListenAndServe(addr, mux)
 }
 
-// CheckFailFast returns an error if fail-fast is enabled and there are invalid required vars.
+// CheckFailFast returns an error if fail-fast is enabled and there are invalid variables,
+// invalid groups, or patterns outside their match limits. Missing required variables, including those made required by
+// a condition, are invalid, and so are variables set while forbidden by a condition or still set past their
+// deprecation fail_after date.
 func CheckFailFast(report *Report, failFast bool) error {
 	if !failFast {
 		return nil
 	}
+	return CheckFailOn(report, SeverityError)
+}
+
+// CheckFailOn is CheckFailFast with a severity threshold: a variable, group, pattern or
+// assertion fails when it is invalid or has a problem at or above threshold. Every variable is
+// checked at every threshold; the threshold only decides which severities count, so warning
+// fails on everything error does plus warnings.
+// Problems are listed as "CODE: message".
+func CheckFailOn(report *Report, threshold Severity) error {
 	var problems []string
 	for _, r := range report.Results {
-		if r.Required && (!r.Present || !r.Valid) {
+		if !r.Valid || hasSeverity(r.Problems, threshold) {
 			msg := fmt.Sprintf("%s: present=%t valid=%t", r.Key, r.Present, r.Valid)
+			if r.RequiredBy != "" {
+				msg += " (" + r.RequiredBy + ")"
+			}
 			if len(r.Problems) > 0 {
-				msg += " problems=[" + strings.Join(r.Problems, "; ") + "]"
+				msg += " problems=[" + strings.Join(problemStrings(r.Problems), "; ") + "]"
 			}
 			problems = append(problems, msg)
 		}
 	}
-	if len(problems) > 0 {
-		return fmt.Errorf("envdoc: fail-fast: %d required variable(s) invalid:\n  %s",
-			len(problems), strings.Join(problems, "\n  "))
+	vars := len(problems)
+	for _, g := range report.Groups {
+		if !g.Valid || hasSeverity(g.Problems, threshold) {
+			msg := fmt.Sprintf("group %s: %s", g.Name, g.Kind)
+			if len(g.Problems) > 0 {
+				msg += " problems=[" + strings.Join(problemStrings(g.Problems), "; ") + "]"
+			}
+			problems = append(problems, msg)
+		}
+	}
+	groups := len(problems) - vars
+	for _, p := range report.Patterns {
+		if !p.Valid || hasSeverity(p.Problems, threshold) {
+			msg := fmt.Sprintf("pattern %s: matches=%d", p.Pattern, p.Matches)
+			if len(p.Problems) > 0 {
+				msg += " problems=[" + strings.Join(problemStrings(p.Problems), "; ") + "]"
+			}
+			problems = append(problems, msg)
+		}
+	}
+	patterns := len(problems) - vars - groups
+	for _, a := range report.Assertions {
+		if !a.Valid || hasSeverity(a.Problems, threshold) {
+			problems = append(problems, fmt.Sprintf("assertion %s: problems=[%s]", a.Name, strings.Join(problemStrings(a.Problems), "; ")))
+		}
+	}
+	assertions := len(problems) - vars - groups - patterns
+	if len(problems) == 0 {
+		return nil
+	}
+
+	var counts []string
+	if vars > 0 {
+		counts = append(counts, fmt.Sprintf("%d variable(s) invalid", vars))
+	}
+	if groups > 0 {
+		counts = append(counts, fmt.Sprintf("%d group(s) invalid", groups))
+	}
+	if patterns > 0 {
+		counts = append(counts, fmt.Sprintf("%d pattern(s) invalid", patterns))
+	}
+	if assertions > 0 {
+		counts = append(counts, fmt.Sprintf("%d assertion(s) failed", assertions))
 	}
-	return nil
+	return fmt.Errorf("envdoc: fail-fast: %s:\n  %s",
+		strings.Join(counts, ", "), strings.Join(problems, "\n  "))
 }
@@ -2,6 +2,7 @@ package envdoc
 
 import (
 	"bytes"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -92,8 +93,8 @@ func TestCheckFailFast_Disabled(t *testing.T) {
 func TestCheckFailFast_MultipleFailures(t *testing.T) {
 	report := &Report{
 		Results: []VarResult{
-			{Key: "A", Present: false, Valid: false, Required: true, Problems: []string{"required but not set"}},
-			{Key: "B", Present: true, Valid: false, Required: true, Problems: []string{"not a valid int"}},
+			{Key: "A", Present: false, Valid: false, Required: true, Problems: []Problem{{Field: "required", Severity: SeverityError, Message: "required but not set"}}},
+			{Key: "B", Present: true, Valid: false, Required: true, Problems: []Problem{{Field: "type", Severity: SeverityError, Message: "not a valid int"}}},
 			{Key: "C", Present: true, Valid: true, Required: false},
 		},
 	}
@@ -101,7 +102,7 @@ func TestCheckFailFast_MultipleFailures(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error")
 	}
-	if !strings.Contains(err.Error(), "2 required variable(s) invalid") {
+	if !strings.Contains(err.Error(), "2 variable(s) invalid") {
 		t.Errorf("expected 2 failures, got: %v", err)
 	}
 }
@@ -145,6 +146,13 @@ func TestNew_DefaultDumpAll_WhenNoRules(t *testing.T) {
 	}
 }
 
+func TestNew_NilRuleSet(t *testing.T) {
+	inspector := New(WithEnvReader(MapEnvReader{"FOO": "bar"}), WithRuleSet(nil))
+	if cfg := inspector.Config(); cfg.Mode != ModeDumpAll {
+		t.Errorf("expected a nil rule set to mean no rules, got mode %s", cfg.Mode)
+	}
+}
+
 func TestNew_AllowlistMode_WhenRulesProvided(t *testing.T) {
 	rules := []Rule{{Key: "DB_HOST", Required: true}}
 	inspector := New(
@@ -162,6 +170,54 @@ func TestNew_AllowlistMode_WhenRulesProvided(t *testing.T) {
 	}
 }
 
+func TestNew_ProfileFromConfig(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/profiles/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := MapEnvReader{"ENVDOC_PROFILE": "prod"}
+	report, err := New(WithEnvReader(env), WithRuleSet(rs), WithOutput(io.Discard)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Profile != "prod" || report.Summary.Missing != 2 {
+		t.Errorf("expected the prod profile applied, got profile %q and %+v", report.Profile, report.Summary)
+	}
+	if rs.ActiveProfile != "" || rs.Rules[1].Required {
+		t.Error("expected the caller's rule set to be left unpatched")
+	}
+
+	// A profile applied by the caller is kept.
+	if err := rs.ApplyProfile("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if report := New(WithEnvReader(env), WithRuleSet(rs)).Inspect(); report.Profile != "dev" {
+		t.Errorf("expected the applied dev profile to be kept, got %q", report.Profile)
+	}
+
+	// Without rules there is nothing to apply it to.
+	if _, err := New(WithEnvReader(env), WithOutput(io.Discard)).Run(); err != nil {
+		t.Errorf("expected dump-all mode to ignore the profile, got %v", err)
+	}
+}
+
+func TestNew_UnknownProfile(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/profiles/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := New(WithEnvReader(MapEnvReader{"ENVDOC_PROFILE": "qa"}), WithRuleSet(rs), WithOutput(io.Discard))
+	if _, err := i.Run(); err == nil || !strings.Contains(err.Error(), `unknown profile "qa"`) {
+		t.Errorf("expected Run to report the unknown profile, got %v", err)
+	}
+	var cfg struct {
+		Level string `env:"LOG_LEVEL"`
+	}
+	if _, err := i.Bind(&cfg); err == nil || !strings.Contains(err.Error(), `unknown profile "qa"`) {
+		t.Errorf("expected Bind to report the unknown profile, got %v", err)
+	}
+}
+
 func TestDumpAllMode_WithFingerprints(t *testing.T) {
 	env := MapEnvReader{
 		"APP_NAME":   "myapp",
@@ -0,0 +1,700 @@
+package envdoc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expressions are small boolean predicates over environment variables, used
+// by Rule.Expr and top-level assertions. They have no loops, assignments or
+// side effects. The grammar is:
+//
+//	expr    = "if" expr "then" expr [ "else" expr ] | or
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand
+//	                  | "in" "[" literal { "," literal } "]" ]
+//	operand = literal | NAME | func "(" args ")" | "(" expr ")"
+//
+// NAME is an environment variable, typed by its rule: int and port are int,
+// float is float, bool is bool, duration is duration, list is list, and
+// everything else is string. A NAME without a rule of its own or a matching
+// pattern rule is an error, so typos do not turn into variables that are
+// never set; set(NAME) accepts any name. Literals are numbers, durations such as 30s,
+// quoted strings, true and false. Functions are len(x) for strings and
+// lists, matches(x, "regex") and set(NAME).
+
+// exprType is the static type of an expression.
+type exprType int
+
+const (
+	exprBool exprType = iota + 1
+	exprInt
+	exprFloat
+	exprString
+	exprDuration
+	exprList
+)
+
+var exprTypeNames = map[exprType]string{
+	exprBool: "bool", exprInt: "int", exprFloat: "float",
+	exprString: "string", exprDuration: "duration", exprList: "list",
+}
+
+func (t exprType) String() string { return exprTypeNames[t] }
+
+// exprTypeOf returns the expression type of a variable with rule.
+func exprTypeOf(rule Rule) exprType {
+	switch rule.Type {
+	case TypeInt, TypePort:
+		return exprInt
+	case TypeFloat:
+		return exprFloat
+	case TypeBool:
+		return exprBool
+	case TypeDuration:
+		return exprDuration
+	case TypeList:
+		return exprList
+	}
+	return exprString
+}
+
+// errExprSkipped means an expression read a variable that is unset or does
+// not parse as its type, so it cannot be decided.
+var errExprSkipped = errors.New("variable unset or invalid")
+
+// compiledExpr is a parsed and type-checked boolean expression.
+type compiledExpr struct {
+	src  string
+	root exprNode
+}
+
+// compileExpr parses src and type-checks it against rules. The result must
+// be a bool.
+func compileExpr(src string, rules []Rule) (*compiledExpr, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks, rules: rules, ruleMap: keyedRules(rules)}
+	root, typ, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+	}
+	if typ != exprBool {
+		return nil, fmt.Errorf("expression is %s, want bool", typ)
+	}
+	return &compiledExpr{src: src, root: root}, nil
+}
+
+// eval evaluates the expression against env. It returns errExprSkipped if
+// the expression reads an unset or invalid variable.
+func (e *compiledExpr) eval(env EnvReader) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Lexer
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+)
+
+type exprToken struct {
+	kind tokKind
+	text string
+	val  any
+	pos  int
+}
+
+func (t exprToken) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+var exprOps = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lexExpr(src string) ([]exprToken, error) {
+	var toks []exprToken
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isAlnum(src[i])) {
+				i++
+			}
+			toks = append(toks, exprToken{kind: tokIdent, text: src[start:i], pos: start})
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			if i < len(src) && unicode.IsLetter(rune(src[i])) {
+				// A number followed by a unit is a duration such as 1h30m.
+				for i < len(src) && (isAlnum(src[i]) || src[i] == '.') {
+					i++
+				}
+				d, err := time.ParseDuration(src[start:i])
+				if err != nil {
+					return nil, fmt.Errorf("invalid duration %q at offset %d", src[start:i], start)
+				}
+				toks = append(toks, exprToken{kind: tokDuration, text: src[start:i], val: d, pos: start})
+				continue
+			}
+			text := src[start:i]
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				toks = append(toks, exprToken{kind: tokNumber, text: text, val: n, pos: start})
+			} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+				toks = append(toks, exprToken{kind: tokNumber, text: text, val: f, pos: start})
+			} else {
+				return nil, fmt.Errorf("invalid number %q at offset %d", text, start)
+			}
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(src) && rune(src[i]) != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteByte(src[i])
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			toks = append(toks, exprToken{kind: tokString, text: src[start:i], val: sb.String(), pos: start})
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, exprToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, exprToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isAlnum(b byte) bool {
+	return b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// Parser
+
+type exprParser struct {
+	toks    []exprToken
+	pos     int
+	rules   []Rule
+	ruleMap map[string]Rule
+}
+
+// lookup returns the rule for the variable name: its own rule, or else the
+// first pattern rule that matches it.
+func (p *exprParser) lookup(name string) (Rule, bool) {
+	if r, ok := p.ruleMap[name]; ok {
+		return r, true
+	}
+	for _, r := range p.rules {
+		if r.isPattern() && r.Matches(name) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (p *exprParser) peek() exprToken { return p.toks[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword text.
+func (p *exprParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == tokOp || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		return fmt.Errorf("expected %q, got %s at offset %d", text, t, t.pos)
+	}
+	return nil
+}
+
+func (p *exprParser) parseExpr() (exprNode, exprType, error) {
+	if !p.accept("if") {
+		return p.parseOr()
+	}
+	cond, err := p.parseBool("if")
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, 0, err
+	}
+	then, err := p.parseBool("then")
+	if err != nil {
+		return nil, 0, err
+	}
+	var els exprNode = litNode{true}
+	if p.accept("else") {
+		if els, err = p.parseBool("else"); err != nil {
+			return nil, 0, err
+		}
+	}
+	return ifNode{cond, then, els}, exprBool, nil
+}
+
+// parseBool parses an expression that must be a bool.
+func (p *exprParser) parseBool(what string) (exprNode, error) {
+	pos := p.peek().pos
+	n, typ, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if typ != exprBool {
+		return nil, fmt.Errorf("%s operand at offset %d is %s, want bool", what, pos, typ)
+	}
+	return n, nil
+}
+
+func (p *exprParser) parseOr() (exprNode, exprType, error) {
+	return p.parseLogic("||", p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, exprType, error) {
+	return p.parseLogic("&&", p.parseNot)
+}
+
+func (p *exprParser) parseLogic(op string, operand func() (exprNode, exprType, error)) (exprNode, exprType, error) {
+	pos := p.peek().pos
+	l, lt, err := operand()
+	if err != nil {
+		return nil, 0, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == op {
+		p.next()
+		rpos := p.peek().pos
+		r, rt, err := operand()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lt != exprBool {
+			return nil, 0, fmt.Errorf("%s operand at offset %d is %s, want bool", op, pos, lt)
+		}
+		if rt != exprBool {
+			return nil, 0, fmt.Errorf("%s operand at offset %d is %s, want bool", op, rpos, rt)
+		}
+		l = logicNode{op == "&&", l, r}
+	}
+	return l, lt, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, exprType, error) {
+	if p.peek().kind == tokOp && p.peek().text == "!" {
+		pos := p.next().pos
+		x, t, err := p.parseNot()
+		if err != nil {
+			return nil, 0, err
+		}
+		if t != exprBool {
+			return nil, 0, fmt.Errorf("! operand at offset %d is %s, want bool", pos, t)
+		}
+		return notNode{x}, exprBool, nil
+	}
+	return p.parseCompare()
+}
+
+var compareOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *exprParser) parseCompare() (exprNode, exprType, error) {
+	l, lt, err := p.parseOperand()
+	if err != nil {
+		return nil, 0, err
+	}
+	t := p.peek()
+	if t.kind == tokIdent && t.text == "in" {
+		p.next()
+		return p.parseIn(l, lt, t.pos)
+	}
+	if t.kind != tokOp || !compareOps[t.text] {
+		return l, lt, nil
+	}
+	p.next()
+	r, rt, err := p.parseOperand()
+	if err != nil {
+		return nil, 0, err
+	}
+	typ, ok := unifyTypes(lt, rt)
+	if !ok {
+		return nil, 0, fmt.Errorf("cannot compare %s with %s at offset %d", lt, rt, t.pos)
+	}
+	ordered := t.text != "==" && t.text != "!="
+	if ordered && typ != exprInt && typ != exprFloat && typ != exprDuration {
+		return nil, 0, fmt.Errorf("operator %s at offset %d needs numbers or durations, got %s", t.text, t.pos, typ)
+	}
+	if typ == exprList {
+		return nil, 0, fmt.Errorf("cannot compare lists at offset %d", t.pos)
+	}
+	return compareNode{t.text, l, r, typ == exprFloat}, exprBool, nil
+}
+
+func (p *exprParser) parseIn(x exprNode, xt exprType, pos int) (exprNode, exprType, error) {
+	if err := p.expect("["); err != nil {
+		return nil, 0, err
+	}
+	n := inNode{x: x}
+	for {
+		lit, lt, err := p.parseLiteral()
+		if err != nil {
+			return nil, 0, err
+		}
+		typ, ok := unifyTypes(xt, lt)
+		if !ok || typ == exprList {
+			return nil, 0, fmt.Errorf("cannot test %s in list of %s at offset %d", xt, lt, pos)
+		}
+		if typ == exprFloat {
+			n.float = true
+		}
+		n.set = append(n.set, lit)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, 0, err
+	}
+	// Like a comparison, the test is done as floats if any operand is one.
+	if n.float {
+		for i, lit := range n.set {
+			n.set[i] = toFloat(lit)
+		}
+	}
+	return n, exprBool, nil
+}
+
+// unifyTypes returns the type two operands are compared as. Ints compare
+// with floats as floats.
+func unifyTypes(a, b exprType) (exprType, bool) {
+	if a == b {
+		return a, true
+	}
+	if (a == exprInt && b == exprFloat) || (a == exprFloat && b == exprInt) {
+		return exprFloat, true
+	}
+	return 0, false
+}
+
+func (p *exprParser) parseLiteral() (any, exprType, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		if _, ok := t.val.(int64); ok {
+			return t.val, exprInt, nil
+		}
+		return t.val, exprFloat, nil
+	case tokDuration:
+		return t.val, exprDuration, nil
+	case tokString:
+		return t.val, exprString, nil
+	case tokIdent:
+		if t.text == "true" || t.text == "false" {
+			return t.text == "true", exprBool, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("expected literal, got %s at offset %d", t, t.pos)
+}
+
+func (p *exprParser) parseOperand() (exprNode, exprType, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber, tokDuration, tokString:
+		v, typ, err := p.parseLiteral()
+		return litNode{v}, typ, err
+	case tokOp:
+		if t.text == "(" {
+			p.next()
+			n, typ, err := p.parseExpr()
+			if err != nil {
+				return nil, 0, err
+			}
+			return n, typ, p.expect(")")
+		}
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			v, typ, err := p.parseLiteral()
+			return litNode{v}, typ, err
+		case "if", "then", "else", "in":
+			return nil, 0, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+		}
+		p.next()
+		if p.peek().kind == tokOp && p.peek().text == "(" {
+			return p.parseCall(t)
+		}
+		rule, ok := p.lookup(t.text)
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown variable %q at offset %d: no rule declares it (use set(%s) to test an undeclared variable)", t.text, t.pos, t.text)
+		}
+		rule.Key = t.text
+		return varNode{rule, exprTypeOf(rule)}, exprTypeOf(rule), nil
+	}
+	return nil, 0, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+func (p *exprParser) parseCall(name exprToken) (exprNode, exprType, error) {
+	p.next() // (
+	switch name.text {
+	case "set":
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, 0, fmt.Errorf("set() at offset %d takes a variable name", name.pos)
+		}
+		return setNode{t.text}, exprBool, p.expect(")")
+	case "len":
+		x, xt, err := p.parseExpr()
+		if err != nil {
+			return nil, 0, err
+		}
+		if xt != exprString && xt != exprList {
+			return nil, 0, fmt.Errorf("len() at offset %d takes a string or list, got %s", name.pos, xt)
+		}
+		return lenNode{x}, exprInt, p.expect(")")
+	case "matches":
+		x, xt, err := p.parseExpr()
+		if err != nil {
+			return nil, 0, err
+		}
+		if xt != exprString {
+			return nil, 0, fmt.Errorf("matches() at offset %d takes a string, got %s", name.pos, xt)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, 0, err
+		}
+		t := p.next()
+		if t.kind != tokString {
+			return nil, 0, fmt.Errorf("matches() at offset %d needs a quoted regex", name.pos)
+		}
+		re, err := regexp.Compile(t.val.(string))
+		if err != nil {
+			return nil, 0, fmt.Errorf("matches() at offset %d: invalid regex: %w", name.pos, err)
+		}
+		return matchNode{x, re}, exprBool, p.expect(")")
+	}
+	return nil, 0, fmt.Errorf("unknown function %q at offset %d", name.text, name.pos)
+}
+
+// Evaluation
+
+type exprNode interface {
+	eval(env EnvReader) (any, error)
+}
+
+type litNode struct{ v any }
+
+func (n litNode) eval(EnvReader) (any, error) { return n.v, nil }
+
+// varNode reads a variable and parses it as its rule type.
+type varNode struct {
+	rule Rule
+	typ  exprType
+}
+
+func (n varNode) eval(env EnvReader) (any, error) {
+	value, ok := env.LookupEnv(n.rule.Key)
+	if !ok {
+		return nil, errExprSkipped
+	}
+	switch n.typ {
+	case exprString:
+		return value, nil
+	case exprList:
+		return splitList(value, n.rule.listSeparator()), nil
+	}
+	v, err := parseValue(value, n.rule.Type)
+	if err != nil {
+		return nil, errExprSkipped
+	}
+	return v, nil
+}
+
+type setNode struct{ key string }
+
+func (n setNode) eval(env EnvReader) (any, error) {
+	_, ok := env.LookupEnv(n.key)
+	return ok, nil
+}
+
+type notNode struct{ x exprNode }
+
+func (n notNode) eval(env EnvReader) (any, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+// logicNode is a short-circuit && (and) or ||.
+type logicNode struct {
+	and  bool
+	l, r exprNode
+}
+
+func (n logicNode) eval(env EnvReader) (any, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if l.(bool) != n.and {
+		return l, nil
+	}
+	return n.r.eval(env)
+}
+
+// ifNode evaluates then or els depending on cond.
+type ifNode struct{ cond, then, els exprNode }
+
+func (n ifNode) eval(env EnvReader) (any, error) {
+	c, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if c.(bool) {
+		return n.then.eval(env)
+	}
+	return n.els.eval(env)
+}
+
+type compareNode struct {
+	op    string
+	l, r  exprNode
+	float bool
+}
+
+func (n compareNode) eval(env EnvReader) (any, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.float {
+		l, r = toFloat(l), toFloat(r)
+	}
+	switch n.op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+	c := compareParsed(l, r)
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+type inNode struct {
+	x     exprNode
+	set   []any
+	float bool
+}
+
+func (n inNode) eval(env EnvReader) (any, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.float {
+		v = toFloat(v)
+	}
+	for _, s := range n.set {
+		if v == s {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type lenNode struct{ x exprNode }
+
+func (n lenNode) eval(env EnvReader) (any, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if items, ok := v.([]string); ok {
+		return int64(len(items)), nil
+	}
+	return int64(len(v.(string))), nil
+}
+
+type matchNode struct {
+	x  exprNode
+	re *regexp.Regexp
+}
+
+func (n matchNode) eval(env EnvReader) (any, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return n.re.MatchString(v.(string)), nil
+}
+
+// toFloat widens an int64 to float64 for mixed comparisons.
+func toFloat(v any) any {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v
+}
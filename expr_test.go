@@ -0,0 +1,124 @@
+package envdoc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func exprRules() []Rule {
+	return []Rule{
+		{Key: "REPLICAS", Type: TypeInt},
+		{Key: "RATIO", Type: TypeFloat},
+		{Key: "TLS_ENABLED", Type: TypeBool},
+		{Key: "TIMEOUT", Type: TypeDuration},
+		{Key: "BROKERS", Type: TypeList},
+		{Key: "CACHE_BACKEND", Allowed: []string{"memory", "redis"}},
+		{Key: "REGION"},
+		{Key: "MISSING"},
+		{KeyPattern: "LIMIT_*", Type: TypeInt},
+	}
+}
+
+func TestCompileExpr_Eval(t *testing.T) {
+	env := MapEnvReader{
+		"REPLICAS":      "3",
+		"RATIO":         "0.5",
+		"TLS_ENABLED":   "1",
+		"TIMEOUT":       "1m30s",
+		"BROKERS":       "k1:9092, k2:9092",
+		"CACHE_BACKEND": "memory",
+		"REGION":        "eu-west-1",
+		"LIMIT_CPU":     "4",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"REPLICAS > 1", true},
+		{"REPLICAS >= 3 && REPLICAS <= 3", true},
+		{"REPLICAS == 3.0", true},
+		{"RATIO < 1", true},
+		{"TLS_ENABLED == true", true},
+		{"TLS_ENABLED", true},
+		{"!TLS_ENABLED || REPLICAS < 0", false},
+		{"TIMEOUT > 1m && TIMEOUT < 2m", true},
+		{"len(BROKERS) == 2", true},
+		{"len(REGION) > 20", false},
+		{`matches(REGION, "^[a-z]+-[a-z]+-[0-9]$")`, true},
+		{"CACHE_BACKEND in ['memory', 'local']", true},
+		{"REPLICAS in [1, 2]", false},
+		{"REPLICAS in [3.0, 2.5]", true},
+		{"REPLICAS in [3, 2.5]", true},
+		{"RATIO in [1, 2]", false},
+		{"if REPLICAS > 1 then CACHE_BACKEND != 'memory'", false},
+		{"if REPLICAS > 5 then CACHE_BACKEND != 'memory'", true},
+		{"if REPLICAS > 5 then false else REPLICAS == 3", true},
+		{"set(REGION) && !set(MISSING) && !set(UNDECLARED)", true},
+		{"LIMIT_CPU > 2", true},
+		{"(REPLICAS > 1 || MISSING == 'x') && true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := compileExpr(tt.expr, exprRules())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.eval(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExpr_Skipped(t *testing.T) {
+	env := MapEnvReader{"REPLICAS": "many"}
+	for _, src := range []string{"REPLICAS > 1", "MISSING == 'x'", "if MISSING == 'x' then true"} {
+		e, err := compileExpr(src, exprRules())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.eval(env); !errors.Is(err, errExprSkipped) {
+			t.Errorf("%s: expected skip, got %v", src, err)
+		}
+	}
+}
+
+func TestCompileExpr_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"REPLICAS", "expression is int, want bool"},
+		{"REPLICAS > 'three'", "cannot compare int with string"},
+		{"CACHE_BACKEND < 'z'", "needs numbers or durations"},
+		{"TIMEOUT > 5", "cannot compare duration with int"},
+		{"REPLICAS && TLS_ENABLED", "&& operand at offset 0 is int"},
+		{"len(REPLICAS) > 1", "len() at offset 0 takes a string or list"},
+		{"matches(CACHE_BACKEND, '[')", "invalid regex"},
+		{"matches(CACHE_BACKEND, CACHE_BACKEND)", "needs a quoted regex"},
+		{"exec('rm')", `unknown function "exec"`},
+		{"REPLICAS > 1 )", `unexpected ")"`},
+		{"REPLICAS >", "unexpected end of expression"},
+		{"'open", "unterminated string"},
+		{"TIMEOUT > 5parsecs", "invalid duration"},
+		{"REPLICAS $ 1", "unexpected character"},
+		{"REPLICAS * 4 > 1", "unexpected character '*'"},
+		{"if TLS_ENABLED then REPLICAS", "then operand"},
+		{"BROKERS == BROKERS", "cannot compare lists"},
+		{"CACHE_BACKNED != 'memory'", `unknown variable "CACHE_BACKNED" at offset 0: no rule declares it`},
+		{"LIMIT_CPU == 'x'", "cannot compare int with string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compileExpr(tt.expr, exprRules())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package envdoc
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GroupKind names the constraint a Group applies to its members.
+type GroupKind string
+
+const (
+	GroupOneOf             GroupKind = "one_of"
+	GroupAnyOf             GroupKind = "any_of"
+	GroupAllOrNone         GroupKind = "all_or_none"
+	GroupMutuallyExclusive GroupKind = "mutually_exclusive"
+)
+
+// KeySet is a group member: one or more keys that are set together. In YAML
+// it is written as a single key or a list of keys.
+type KeySet []string
+
+// UnmarshalYAML accepts either a scalar key or a sequence of keys.
+func (k *KeySet) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = KeySet{node.Value}
+		return nil
+	}
+	var keys []string
+	if err := node.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// MarshalYAML writes single-key sets as a scalar.
+func (k KeySet) MarshalYAML() (any, error) {
+	if len(k) == 1 {
+		return k[0], nil
+	}
+	return []string(k), nil
+}
+
+// String joins the keys with "+".
+func (k KeySet) String() string {
+	return strings.Join(k, "+")
+}
+
+// Group constrains which of several variables may be set together. Exactly
+// one of OneOf, AnyOf, AllOrNone and MutuallyExclusive must be set:
+//
+//   - one_of: exactly one member is fully set and no other member is touched
+//   - any_of: at least one member is fully set
+//   - all_or_none: either every key is set or none is
+//   - mutually_exclusive: at most one member is touched
+//
+// A member is touched when any of its keys is set and fully set when all are.
+type Group struct {
+	Name              string   `yaml:"name"`
+	OneOf             []KeySet `yaml:"one_of,omitempty"`
+	AnyOf             []KeySet `yaml:"any_of,omitempty"`
+	AllOrNone         KeySet   `yaml:"all_or_none,omitempty"`
+	MutuallyExclusive []KeySet `yaml:"mutually_exclusive,omitempty"`
+}
+
+// Kind returns the group's constraint, or "" if none is set.
+func (g Group) Kind() GroupKind {
+	switch {
+	case len(g.OneOf) > 0:
+		return GroupOneOf
+	case len(g.AnyOf) > 0:
+		return GroupAnyOf
+	case len(g.AllOrNone) > 0:
+		return GroupAllOrNone
+	case len(g.MutuallyExclusive) > 0:
+		return GroupMutuallyExclusive
+	}
+	return ""
+}
+
+// members returns the group's members; all_or_none has one member per key.
+func (g Group) members() []KeySet {
+	switch g.Kind() {
+	case GroupOneOf:
+		return g.OneOf
+	case GroupAnyOf:
+		return g.AnyOf
+	case GroupMutuallyExclusive:
+		return g.MutuallyExclusive
+	case GroupAllOrNone:
+		members := make([]KeySet, len(g.AllOrNone))
+		for i, k := range g.AllOrNone {
+			members[i] = KeySet{k}
+		}
+		return members
+	}
+	return nil
+}
+
+// GroupResult holds the inspection result for a single Group.
+type GroupResult struct {
+	Name     string    `json:"name"`
+	Kind     GroupKind `json:"kind"`
+	Keys     []string  `json:"keys"`
+	Valid    bool      `json:"valid"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// memberState records which keys of a member are set.
+type memberState struct {
+	keys    KeySet
+	missing []string
+}
+
+func (m memberState) touched() bool  { return len(m.missing) < len(m.keys) }
+func (m memberState) complete() bool { return len(m.missing) == 0 }
+
+// inspectGroup evaluates a group against env. Only presence is examined.
+func inspectGroup(env EnvReader, g Group) GroupResult {
+	gr := GroupResult{Name: g.Name, Kind: g.Kind(), Valid: true}
+
+	var states []memberState
+	for _, m := range g.members() {
+		st := memberState{keys: m}
+		for _, k := range m {
+			gr.Keys = append(gr.Keys, k)
+			if _, ok := env.LookupEnv(k); !ok {
+				st.missing = append(st.missing, k)
+			}
+		}
+		states = append(states, st)
+	}
+
+	var touched, complete []string
+	var incomplete []memberState
+	for _, st := range states {
+		if st.touched() {
+			touched = append(touched, st.keys.String())
+		}
+		if st.complete() {
+			complete = append(complete, st.keys.String())
+		} else if st.touched() {
+			incomplete = append(incomplete, st)
+		}
+	}
+
+	switch gr.Kind {
+	case GroupOneOf:
+		if len(touched) == 0 {
+			gr.addProblem(CodeGroupNoneSet, "none of the alternatives is set")
+		}
+		if len(touched) > 1 {
+			gr.addProblem(CodeGroupMultiple, "multiple alternatives set: "+strings.Join(touched, ", "))
+		}
+		gr.addIncomplete(incomplete)
+	case GroupAnyOf:
+		if len(complete) == 0 && len(incomplete) == 0 {
+			gr.addProblem(CodeGroupNoneSet, "none of the alternatives is set")
+		}
+		gr.addIncomplete(incomplete)
+	case GroupAllOrNone:
+		if len(touched) > 0 && len(complete) < len(states) {
+			var missing []string
+			for _, st := range states {
+				missing = append(missing, st.missing...)
+			}
+			gr.addProblem(CodeGroupIncomplete, "partially set: missing "+strings.Join(missing, ", "))
+		}
+	case GroupMutuallyExclusive:
+		if len(touched) > 1 {
+			gr.addProblem(CodeGroupExclusive, "mutually exclusive but set together: "+strings.Join(touched, ", "))
+		}
+	}
+
+	gr.Valid = len(gr.Problems) == 0
+	return gr
+}
+
+// addProblem records an error-level problem on the group. Field is the
+// group kind and Expected lists the group's keys.
+func (gr *GroupResult) addProblem(code ProblemCode, msg string) {
+	gr.Problems = append(gr.Problems, Problem{
+		Code: code, Field: string(gr.Kind), Severity: SeverityError, Expected: gr.Keys, Message: msg,
+	})
+}
+
+func (gr *GroupResult) addIncomplete(states []memberState) {
+	for _, st := range states {
+		gr.addProblem(CodeGroupIncomplete, fmt.Sprintf("incomplete %s: missing %s", st.keys, strings.Join(st.missing, ", ")))
+	}
+}
+
+// validateGroups checks that every group has a unique name, exactly one
+// constraint, enough members, and no empty or repeated keys.
+func validateGroups(groups []Group) error {
+	seen := make(map[string]bool)
+	for idx, g := range groups {
+		if g.Name == "" {
+			return fmt.Errorf("envdoc: group[%d]: name is required", idx)
+		}
+		if seen[g.Name] {
+			return fmt.Errorf("envdoc: group[%d]: duplicate name %q", idx, g.Name)
+		}
+		seen[g.Name] = true
+
+		kinds := 0
+		for _, set := range []bool{len(g.OneOf) > 0, len(g.AnyOf) > 0, len(g.AllOrNone) > 0, len(g.MutuallyExclusive) > 0} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("envdoc: group[%d] (%s): exactly one of one_of, any_of, all_or_none and mutually_exclusive is required", idx, g.Name)
+		}
+
+		members := g.members()
+		if len(members) < 2 {
+			return fmt.Errorf("envdoc: group[%d] (%s): %s needs at least 2 members", idx, g.Name, g.Kind())
+		}
+		keys := make(map[string]bool)
+		for _, m := range members {
+			if len(m) == 0 {
+				return fmt.Errorf("envdoc: group[%d] (%s): empty member", idx, g.Name)
+			}
+			for _, k := range m {
+				if k == "" {
+					return fmt.Errorf("envdoc: group[%d] (%s): empty key", idx, g.Name)
+				}
+				if keys[k] {
+					return fmt.Errorf("envdoc: group[%d] (%s): key %q appears more than once", idx, g.Name, k)
+				}
+				keys[k] = true
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRuleSet_Groups(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/groups.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 4 || len(rs.Groups) != 2 {
+		t.Fatalf("expected 4 rules and 2 groups, got %d and %d", len(rs.Rules), len(rs.Groups))
+	}
+	g := rs.Groups[0]
+	if g.Kind() != GroupOneOf {
+		t.Errorf("expected one_of, got %s", g.Kind())
+	}
+	if len(g.OneOf) != 2 || len(g.OneOf[0]) != 1 || len(g.OneOf[1]) != 3 {
+		t.Errorf("expected scalar and list members, got %v", g.OneOf)
+	}
+	if rs.Groups[1].Kind() != GroupAllOrNone {
+		t.Errorf("expected all_or_none, got %s", rs.Groups[1].Kind())
+	}
+}
+
+func TestInspectGroup_OneOf(t *testing.T) {
+	g := Group{Name: "database", OneOf: []KeySet{{"DATABASE_URL"}, {"DB_HOST", "DB_PORT", "DB_USER"}}}
+
+	tests := []struct {
+		name string
+		env  MapEnvReader
+		want []string
+	}{
+		{"url only", MapEnvReader{"DATABASE_URL": "postgres://db"}, nil},
+		{"trio only", MapEnvReader{"DB_HOST": "db", "DB_PORT": "5432", "DB_USER": "app"}, nil},
+		{"neither", MapEnvReader{}, []string{"none of the alternatives is set"}},
+		{"both", MapEnvReader{"DATABASE_URL": "x", "DB_HOST": "db", "DB_PORT": "5432", "DB_USER": "app"},
+			[]string{"multiple alternatives set: DATABASE_URL, DB_HOST+DB_PORT+DB_USER"}},
+		{"partial", MapEnvReader{"DB_HOST": "db"}, []string{"incomplete DB_HOST+DB_PORT+DB_USER: missing DB_PORT, DB_USER"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gr := inspectGroup(tt.env, g)
+			if gr.Valid != (len(tt.want) == 0) {
+				t.Errorf("valid=%t, problems=%v", gr.Valid, gr.Problems)
+			}
+			if strings.Join(problemMessages(gr.Problems), "|") != strings.Join(tt.want, "|") {
+				t.Errorf("expected %v, got %v", tt.want, gr.Problems)
+			}
+		})
+	}
+}
+
+func TestInspectGroup_AnyOf(t *testing.T) {
+	g := Group{Name: "auth", AnyOf: []KeySet{{"API_KEY"}, {"OAUTH_ID", "OAUTH_SECRET"}}}
+	if gr := inspectGroup(MapEnvReader{"OAUTH_ID": "a", "OAUTH_SECRET": "b", "API_KEY": "c"}, g); !gr.Valid {
+		t.Errorf("expected valid, got %v", gr.Problems)
+	}
+	if gr := inspectGroup(MapEnvReader{}, g); gr.Valid {
+		t.Error("expected invalid when nothing set")
+	}
+}
+
+func TestInspectGroup_AllOrNone(t *testing.T) {
+	g := Group{Name: "smtp", AllOrNone: KeySet{"SMTP_HOST", "SMTP_USER", "SMTP_PASSWORD"}}
+	if gr := inspectGroup(MapEnvReader{}, g); !gr.Valid {
+		t.Errorf("expected valid when none set, got %v", gr.Problems)
+	}
+	gr := inspectGroup(MapEnvReader{"SMTP_HOST": "mail"}, g)
+	if gr.Valid || len(gr.Problems) != 1 || gr.Problems[0].Message != "partially set: missing SMTP_USER, SMTP_PASSWORD" {
+		t.Errorf("unexpected result: %+v", gr)
+	}
+}
+
+func TestInspectGroup_MutuallyExclusive(t *testing.T) {
+	g := Group{Name: "tls", MutuallyExclusive: []KeySet{{"TLS_CERT_FILE", "TLS_KEY_FILE"}, {"ACME_EMAIL"}}}
+	if gr := inspectGroup(MapEnvReader{}, g); !gr.Valid {
+		t.Errorf("expected valid when none set, got %v", gr.Problems)
+	}
+	if gr := inspectGroup(MapEnvReader{"TLS_CERT_FILE": "a", "ACME_EMAIL": "b"}, g); gr.Valid {
+		t.Error("expected invalid when both members touched")
+	}
+}
+
+func TestRun_FailFastGroups(t *testing.T) {
+	rs := &RuleSet{
+		Groups: []Group{{Name: "database", OneOf: []KeySet{{"DATABASE_URL"}, {"DB_HOST"}}}},
+	}
+	var buf strings.Builder
+	report, err := Run(
+		WithEnvReader(MapEnvReader{"DATABASE_URL": "x", "DB_HOST": "y"}),
+		WithClock(fixedClock{t: time.Now()}),
+		WithRuleSet(rs),
+		WithConfig(Config{Mode: ModeAllowlist, FailFast: true}),
+		WithOutput(&buf),
+	)
+	if err == nil || !strings.Contains(err.Error(), "1 group(s) invalid") {
+		t.Fatalf("expected group fail-fast error, got %v", err)
+	}
+	if len(report.Groups) != 1 || report.Groups[0].Valid {
+		t.Errorf("expected one invalid group result, got %+v", report.Groups)
+	}
+	if !strings.Contains(buf.String(), "group=database kind=one_of valid=false") {
+		t.Errorf("expected group log line, got: %s", buf.String())
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []Group
+		want   string
+	}{
+		{"valid", []Group{{Name: "a", AnyOf: []KeySet{{"A"}, {"B"}}}}, ""},
+		{"no name", []Group{{AnyOf: []KeySet{{"A"}, {"B"}}}}, "name is required"},
+		{"duplicate name", []Group{{Name: "a", AnyOf: []KeySet{{"A"}, {"B"}}}, {Name: "a", AnyOf: []KeySet{{"C"}, {"D"}}}}, "duplicate name"},
+		{"no kind", []Group{{Name: "a"}}, "exactly one of"},
+		{"two kinds", []Group{{Name: "a", AnyOf: []KeySet{{"A"}, {"B"}}, AllOrNone: KeySet{"C", "D"}}}, "exactly one of"},
+		{"one member", []Group{{Name: "a", OneOf: []KeySet{{"A"}}}}, "at least 2 members"},
+		{"repeated key", []Group{{Name: "a", OneOf: []KeySet{{"A"}, {"A", "B"}}}}, "appears more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGroups(tt.groups)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -6,59 +6,95 @@ import (
 )
 
 // VarResult holds the inspection result for a single environment variable.
+// Valid is false when any problem has error severity. Defaulted marks an
+// unset variable whose rule supplies a default. Expired marks a
+// deprecated variable that is still set after its fail_after date.
 type VarResult struct {
-	Key         string   `json:"key"`
-	Present     bool     `json:"present"`
-	Length      int      `json:"length"`
-	Required    bool     `json:"required"`
-	Valid       bool     `json:"valid"`
-	Problems    []string `json:"problems,omitempty"`
-	SecretLike  bool     `json:"secret_like"`
-	Fingerprint string   `json:"fingerprint,omitempty"`
-	Trimmed     bool     `json:"trimmed"`
+	Key         string    `json:"key"`
+	Present     bool      `json:"present"`
+	Defaulted   bool      `json:"defaulted,omitempty"`
+	Length      int       `json:"length"`
+	Required    bool      `json:"required"`
+	RequiredBy  string    `json:"required_by,omitempty"`
+	ForbiddenBy string    `json:"forbidden_by,omitempty"`
+	Valid       bool      `json:"valid"`
+	Problems    []Problem `json:"problems,omitempty"`
+	SecretLike  bool      `json:"secret_like"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Trimmed     bool      `json:"trimmed"`
+	Items       *int      `json:"items,omitempty"`
+	MapKeys     []string  `json:"map_keys,omitempty"`
+	Pattern     string    `json:"pattern,omitempty"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
+	ReplacedBy  string    `json:"replaced_by,omitempty"`
+	Expired     bool      `json:"expired,omitempty"`
 }
 
-// Summary holds aggregate counts.
+// Summary holds aggregate counts. Defaulted counts unset variables that
+// fall back to their rule's default. Errors and Warnings count variable
+// problems by severity.
 type Summary struct {
-	Total    int `json:"total"`
-	Present  int `json:"present"`
-	Valid    int `json:"valid"`
-	Required int `json:"required"`
-	Missing  int `json:"missing"`
+	Total     int `json:"total"`
+	Present   int `json:"present"`
+	Defaulted int `json:"defaulted"`
+	Valid     int `json:"valid"`
+	Required  int `json:"required"`
+	Missing   int `json:"missing"`
+	Errors    int `json:"errors"`
+	Warnings  int `json:"warnings"`
 }
 
 // Report is the complete inspection output.
 type Report struct {
-	Timestamp time.Time   `json:"timestamp"`
-	Mode      string      `json:"mode"`
-	Results   []VarResult `json:"results"`
-	Summary   Summary     `json:"summary"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Mode       string            `json:"mode"`
+	Profile    string            `json:"profile,omitempty"`
+	Results    []VarResult       `json:"results"`
+	Groups     []GroupResult     `json:"groups,omitempty"`
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+	Patterns   []PatternResult   `json:"patterns,omitempty"`
+	Summary    Summary           `json:"summary"`
 }
 
-// inspect performs the core inspection logic.
-func inspect(env EnvReader, clock Clock, rules []Rule, cfg Config) *Report {
+// keyedRules maps explicit rule keys to their rules, skipping pattern rules.
+func keyedRules(rules []Rule) map[string]Rule {
+	ruleMap := make(map[string]Rule, len(rules))
+	for _, r := range rules {
+		if !r.isPattern() {
+			ruleMap[r.Key] = r
+		}
+	}
+	return ruleMap
+}
+
+// inspect performs the core inspection logic. types holds the inspector's
+// own custom type validators.
+func inspect(env EnvReader, clock Clock, rs RuleSet, cfg Config, types typeSet) *Report {
+	rules := rs.Rules
+
 	report := &Report{
 		Timestamp: clock.Now(),
 		Mode:      string(cfg.Mode),
+		Profile:   rs.ActiveProfile,
 	}
 	if report.Mode == "" {
 		report.Mode = "allowlist"
 	}
 
 	// Build rule map for quick lookup
-	ruleMap := make(map[string]Rule)
-	for _, r := range rules {
-		ruleMap[r.Key] = r
+	ruleMap := keyedRules(rules)
+
+	var envKeys []string
+	for _, pair := range env.Environ() {
+		k, _, _ := strings.Cut(pair, "=")
+		envKeys = append(envKeys, k)
 	}
 
 	// Determine which keys to inspect
 	var keys []string
 	if cfg.DumpAll {
 		// Dump-all mode: inspect all env vars
-		for _, pair := range env.Environ() {
-			k, _, _ := strings.Cut(pair, "=")
-			keys = append(keys, k)
-		}
+		keys = append(keys, envKeys...)
 		// Also include rule keys that may not be set
 		for _, r := range rules {
 			if _, ok := ruleMap[r.Key]; ok {
@@ -77,19 +113,45 @@ func inspect(env EnvReader, clock Clock, rules []Rule, cfg Config) *Report {
 	} else {
 		// Allow-list mode: only rule-defined keys
 		for _, r := range rules {
-			keys = append(keys, r.Key)
+			if !r.isPattern() {
+				keys = append(keys, r.Key)
+			}
 		}
 	}
 
+	// Pattern rules apply to env vars without an explicit rule.
+	patternRules, patternKeys, patternResults := expandPatterns(envKeys, rules, ruleMap)
+	if !cfg.DumpAll {
+		keys = append(keys, patternKeys...)
+	}
+	report.Patterns = patternResults
+
 	for _, key := range keys {
-		vr := inspectVar(env, key, ruleMap[key], cfg)
+		rule, matched := patternRules[key]
+		if !matched {
+			rule = ruleMap[key]
+		}
+		vr := inspectVar(env, key, rule, evalRequirement(env, rule, ruleMap), cfg, types)
+		if matched {
+			vr.Pattern = rule.pattern()
+		}
+		checkDefault(&vr, rule)
+		checkDeprecated(env, &vr, rule, report.Timestamp)
+		checkExpr(env, &vr, rule, rules)
 		report.Results = append(report.Results, vr)
+	}
 
+	applyComparisons(env, report.Results, rules, ruleMap)
+
+	for _, vr := range report.Results {
 		// Update summary
 		report.Summary.Total++
 		if vr.Present {
 			report.Summary.Present++
 		}
+		if vr.Defaulted {
+			report.Summary.Defaulted++
+		}
 		if vr.Valid {
 			report.Summary.Valid++
 		}
@@ -99,26 +161,48 @@ func inspect(env EnvReader, clock Clock, rules []Rule, cfg Config) *Report {
 				report.Summary.Missing++
 			}
 		}
+		for _, p := range vr.Problems {
+			switch p.Severity {
+			case SeverityError:
+				report.Summary.Errors++
+			case SeverityWarning:
+				report.Summary.Warnings++
+			}
+		}
+	}
+
+	for _, g := range rs.Groups {
+		report.Groups = append(report.Groups, inspectGroup(env, g))
+	}
+	for _, a := range rs.Assertions {
+		report.Assertions = append(report.Assertions, inspectAssertion(env, a, rules))
 	}
 
 	return report
 }
 
-// inspectVar inspects a single environment variable.
-func inspectVar(env EnvReader, key string, rule Rule, cfg Config) VarResult {
+// inspectVar inspects a single environment variable. req carries the
+// requirement resolved from the rule's conditional clauses.
+func inspectVar(env EnvReader, key string, rule Rule, req requirement, cfg Config, types typeSet) VarResult {
 	vr := VarResult{
-		Key:      key,
-		Required: rule.Required,
-		Valid:    true,
+		Key:         key,
+		Required:    req.required,
+		RequiredBy:  req.requiredBy,
+		ForbiddenBy: req.forbiddenBy,
+		Valid:       true,
 	}
 
 	value, present := env.LookupEnv(key)
 	vr.Present = present
 
 	if !present {
-		if rule.Required {
-			vr.Valid = false
-			vr.Problems = append(vr.Problems, "required but not set")
+		if req.required {
+			// requiredBy is "<field> <condition>" for conditional requirements.
+			field, expected := "required", any(true)
+			if f, cond, ok := strings.Cut(req.requiredBy, " "); ok {
+				field, expected = f, cond
+			}
+			vr.addProblem(rule, newProblem(CodeMissing, field, expected, "required but not set"))
 		}
 		// Classify secret-like even when not present
 		vr.SecretLike = classifySecretLike(key, rule)
@@ -129,14 +213,33 @@ func inspectVar(env EnvReader, key string, rule Rule, cfg Config) VarResult {
 	vr.Trimmed = detectWhitespace(value)
 	vr.SecretLike = classifySecretLike(key, rule)
 
+	if req.forbiddenBy != "" {
+		vr.addProblem(rule, newProblem(CodeForbidden, "forbidden_if", strings.TrimPrefix(req.forbiddenBy, "forbidden_if "), "set but %s", req.forbiddenBy))
+	}
+
+	switch rule.Type {
+	case TypeList:
+		n := len(splitList(value, rule.listSeparator()))
+		vr.Items = &n
+	case TypeMap:
+		// Map keys are structure, not data, but secret-like vars only get a count.
+		pairs := splitMap(value, rule.listSeparator(), rule.kvSeparator())
+		n := len(pairs)
+		vr.Items = &n
+		if !vr.SecretLike {
+			vr.MapKeys = mapKeys(pairs)
+		}
+	}
+
 	// Run validation if rule has any constraints
 	if rule.Key != "" {
-		problems := ValidateVar(value, rule)
-		if len(problems) > 0 {
-			vr.Valid = false
-			vr.Problems = problems
+		for _, p := range checkVar(value, rule, types) {
+			vr.addProblem(rule, p)
 		}
 	}
+	if vr.Trimmed {
+		vr.addProblem(rule, newProblem(CodeWhitespace, "trimmed", nil, "leading or trailing whitespace"))
+	}
 
 	// Fingerprint decision
 	if shouldFingerprint(vr.SecretLike, rule, cfg.DumpAllFingerprint) {
@@ -22,7 +22,7 @@ func TestInspect_AllowlistMode(t *testing.T) {
 	cfg := Config{Mode: ModeAllowlist}
 	clock := fixedClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
 
-	report := inspect(env, clock, rules, cfg)
+	report := inspect(env, clock, RuleSet{Rules: rules}, cfg, nil)
 
 	if report.Mode != "allowlist" {
 		t.Errorf("expected allowlist mode, got %s", report.Mode)
@@ -87,7 +87,7 @@ func TestInspect_MissingRequired(t *testing.T) {
 	cfg := Config{Mode: ModeAllowlist}
 	clock := fixedClock{t: time.Now()}
 
-	report := inspect(env, clock, rules, cfg)
+	report := inspect(env, clock, RuleSet{Rules: rules}, cfg, nil)
 
 	r := report.Results[0]
 	if r.Present {
@@ -113,7 +113,7 @@ func TestInspect_DumpAllMode(t *testing.T) {
 	cfg := Config{Mode: ModeDumpAll, DumpAll: true}
 	clock := fixedClock{t: time.Now()}
 
-	report := inspect(env, clock, nil, cfg)
+	report := inspect(env, clock, RuleSet{}, cfg, nil)
 
 	if report.Mode != "dumpall" {
 		t.Errorf("expected dumpall mode, got %s", report.Mode)
@@ -149,7 +149,7 @@ func TestInspect_ValidationFails(t *testing.T) {
 	cfg := Config{Mode: ModeAllowlist}
 	clock := fixedClock{t: time.Now()}
 
-	report := inspect(env, clock, rules, cfg)
+	report := inspect(env, clock, RuleSet{Rules: rules}, cfg, nil)
 
 	r := report.Results[0]
 	if r.Valid {
@@ -170,9 +170,59 @@ func TestInspect_WhitespaceDetection(t *testing.T) {
 	cfg := Config{}
 	clock := fixedClock{t: time.Now()}
 
-	report := inspect(env, clock, rules, cfg)
+	report := inspect(env, clock, RuleSet{Rules: rules}, cfg, nil)
 
 	if !report.Results[0].Trimmed {
 		t.Error("expected trimmed=true for value with whitespace")
 	}
 }
+
+func TestInspect_ListItemCount(t *testing.T) {
+	env := MapEnvReader{
+		"KAFKA_BROKERS": "k1:9092,k2:9092,k3:9092",
+	}
+	rules := []Rule{
+		{Key: "KAFKA_BROKERS", Type: TypeList, ItemType: TypeHostPort},
+		{Key: "ALLOWED_ORIGINS", Type: TypeList},
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	r := report.Results[0]
+	if r.Items == nil || *r.Items != 3 {
+		t.Errorf("expected 3 items, got %v", r.Items)
+	}
+	if !r.Valid {
+		t.Errorf("expected valid, got problems %v", r.Problems)
+	}
+	if report.Results[1].Items != nil {
+		t.Error("expected no item count for unset list")
+	}
+}
+
+func TestInspect_MapKeys(t *testing.T) {
+	env := MapEnvReader{
+		"OTEL_RESOURCE_ATTRIBUTES": "service.name=api,deployment.environment=prod",
+		"SIGNING_KEYS":             "k1=abc,k2=def,k3=ghi",
+	}
+	rules := []Rule{
+		{Key: "OTEL_RESOURCE_ATTRIBUTES", Type: TypeMap},
+		{Key: "SIGNING_KEYS", Type: TypeMap},
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	r := report.Results[0]
+	if r.Items == nil || *r.Items != 2 {
+		t.Errorf("expected 2 pairs, got %v", r.Items)
+	}
+	if len(r.MapKeys) != 2 || r.MapKeys[0] != "service.name" || r.MapKeys[1] != "deployment.environment" {
+		t.Errorf("expected key names for non-secret map, got %v", r.MapKeys)
+	}
+
+	r = report.Results[1]
+	if r.Items == nil || *r.Items != 3 {
+		t.Errorf("expected 3 pairs, got %v", r.Items)
+	}
+	if r.MapKeys != nil {
+		t.Errorf("expected no key names for secret-like map, got %v", r.MapKeys)
+	}
+}
@@ -0,0 +1,294 @@
+package envdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONSchema is the subset of JSON Schema used to check json values: type,
+// required, properties, enum, items, minimum/maximum, minLength/maxLength,
+// minItems/maxItems and pattern. Other keywords are ignored.
+//
+// In a rules file, json_schema is either an inline schema or the path of a
+// JSON or YAML schema file, relative to the rules file.
+type JSONSchema struct {
+	Type       string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Required   []string               `yaml:"required,omitempty" json:"required,omitempty"`
+	Properties map[string]*JSONSchema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Enum       []any                  `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Items      *JSONSchema            `yaml:"items,omitempty" json:"items,omitempty"`
+	Minimum    *float64               `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum    *float64               `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	MinLength  *int                   `yaml:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength  *int                   `yaml:"maxLength,omitempty" json:"maxLength,omitempty"`
+	MinItems   *int                   `yaml:"minItems,omitempty" json:"minItems,omitempty"`
+	MaxItems   *int                   `yaml:"maxItems,omitempty" json:"maxItems,omitempty"`
+	Pattern    string                 `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+
+	// file is the schema file named in place of an inline schema, until
+	// the loader reads it.
+	file string
+}
+
+// UnmarshalYAML accepts an inline schema or a schema file path.
+func (s *JSONSchema) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = JSONSchema{file: node.Value}
+		return nil
+	}
+	type plain JSONSchema
+	return node.Decode((*plain)(s))
+}
+
+// jsonSchemaTypes are the JSON Schema type names.
+var jsonSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// maxSchemaViolations caps the problems reported for one value.
+const maxSchemaViolations = 10
+
+// schemaViolation is a single failed schema keyword at a JSON pointer path;
+// expected is the keyword's value.
+type schemaViolation struct {
+	path     string
+	expected any
+	message  string
+}
+
+// checkJSONSchema validates a json value against s. Problems name the JSON
+// pointer of the offending element and the keyword, never the value.
+func checkJSONSchema(value string, s *JSONSchema) []Problem {
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil
+	}
+	var violations []schemaViolation
+	s.check(doc, "", &violations)
+
+	var problems []Problem
+	for _, v := range violations {
+		path := v.path
+		if path == "" {
+			path = "(root)"
+		}
+		problems = append(problems, newProblem(CodeJSONSchema, "json_schema", v.expected, "%s: %s", path, v.message))
+	}
+	return problems
+}
+
+// check appends the violations of v, found at path, to out.
+func (s *JSONSchema) check(v any, path string, out *[]schemaViolation) {
+	add := func(expected any, format string, args ...any) {
+		if len(*out) < maxSchemaViolations {
+			*out = append(*out, schemaViolation{path, expected, fmt.Sprintf(format, args...)})
+		}
+	}
+
+	if s.Type != "" && !jsonIsType(v, s.Type) {
+		add(s.Type, "expected %s", s.Type)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(normalizeJSON(e), v) }) {
+		add(s.Enum, "not in enum")
+	}
+
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			add(*s.Minimum, "below minimum %s", formatSchemaNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			add(*s.Maximum, "above maximum %s", formatSchemaNumber(*s.Maximum))
+		}
+	case string:
+		n := utf8.RuneCountInString(x)
+		if s.MinLength != nil && n < *s.MinLength {
+			add(*s.MinLength, "length %d < minLength %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			add(*s.MaxLength, "length %d > maxLength %d", n, *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(x) {
+				add(s.Pattern, "does not match pattern %q", s.Pattern)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(x) < *s.MinItems {
+			add(*s.MinItems, "%d items < minItems %d", len(x), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(x) > *s.MaxItems {
+			add(*s.MaxItems, "%d items > maxItems %d", len(x), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range x {
+				s.Items.check(item, path+"/"+strconv.Itoa(i), out)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				add(s.Required, "missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if pv, ok := x[name]; ok {
+				s.Properties[name].check(pv, path+"/"+jsonPointerEscape(name), out)
+			}
+		}
+	}
+}
+
+// jsonIsType reports whether a decoded JSON value is of the schema type typ.
+// Integers are numbers without a fractional part, so 1.0 is an integer.
+func jsonIsType(v any, typ string) bool {
+	switch x := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		if typ == "integer" {
+			f, err := x.Float64()
+			return err == nil && f == math.Trunc(f)
+		}
+	}
+	return false
+}
+
+// normalizeJSON converts a schema value decoded from YAML into the form
+// checkJSONSchema decodes values to, so the two can be compared.
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return v
+	}
+	return out
+}
+
+// jsonEqual reports whether two decoded JSON values are equal. Numbers
+// compare by value, so 1 equals 1.0.
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, jsonEqual)
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			yv, ok := y[k]
+			if !ok || !jsonEqual(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// jsonPointerEscape escapes a property name for a JSON pointer (RFC 6901).
+func jsonPointerEscape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+func formatSchemaNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// validateJSONSchema checks a schema's keywords. path is the schema
+// location for error messages.
+func validateJSONSchema(s *JSONSchema, path string) error {
+	where := "json_schema"
+	if path != "" {
+		where += " " + path
+	}
+	if s == nil {
+		return fmt.Errorf("%s: schema is empty", where)
+	}
+	if s.file != "" {
+		return fmt.Errorf("%s: schema files are only allowed at the top level", where)
+	}
+	if s.Type != "" && !jsonSchemaTypes[s.Type] {
+		return fmt.Errorf("%s: unknown type %q", where, s.Type)
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", where, err)
+		}
+	}
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return fmt.Errorf("%s: minimum > maximum", where)
+	}
+	for _, b := range []struct {
+		name     string
+		min, max *int
+	}{{"Length", s.MinLength, s.MaxLength}, {"Items", s.MinItems, s.MaxItems}} {
+		if (b.min != nil && *b.min < 0) || (b.max != nil && *b.max < 0) {
+			return fmt.Errorf("%s: min%s and max%s must be >= 0", where, b.name, b.name)
+		}
+		if b.min != nil && b.max != nil && *b.min > *b.max {
+			return fmt.Errorf("%s: min%s > max%s", where, b.name, b.name)
+		}
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := validateJSONSchema(s.Properties[name], path+"/properties/"+jsonPointerEscape(name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := validateJSONSchema(s.Items, path+"/items"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
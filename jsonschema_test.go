@@ -0,0 +1,134 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckJSONSchema(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(`
+rules:
+  - key: FEATURE_FLAGS
+    type: json
+    json_schema:
+      type: object
+      required: [name, limits]
+      properties:
+        name: {type: string, minLength: 3, maxLength: 8, pattern: "^[a-z]+$"}
+        limits:
+          type: object
+          properties:
+            rps: {type: integer, minimum: 1, maximum: 100}
+            a/b: {type: boolean}
+        tags: {type: array, minItems: 1, maxItems: 2, items: {type: string}}
+        mode: {enum: [on, off, 1]}
+        ratio: {type: number}
+        extra: {type: "null"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := rs.Rules[0]
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{`{"name":"api","limits":{"rps":10},"tags":["a"],"mode":"on","ratio":0.5,"extra":null}`, nil},
+		{`{"name":"api","limits":{"rps":10.0},"mode":1.0}`, nil},
+		{`[]`, []string{"(root): expected object"}},
+		{`{"limits":{}}`, []string{`(root): missing required property "name"`}},
+		{`{"name":"api","limits":{"rps":"10"}}`, []string{"/limits/rps: expected integer"}},
+		{`{"name":"api","limits":{"rps":1.5}}`, []string{"/limits/rps: expected integer"}},
+		{`{"name":"api","limits":{"rps":0}}`, []string{"/limits/rps: below minimum 1"}},
+		{`{"name":"api","limits":{"rps":101}}`, []string{"/limits/rps: above maximum 100"}},
+		{`{"name":"api","limits":{"a/b":1}}`, []string{"/limits/a~1b: expected boolean"}},
+		{`{"name":"ab","limits":{}}`, []string{"/name: length 2 < minLength 3"}},
+		{`{"name":"abcdefghi","limits":{}}`, []string{"/name: length 9 > maxLength 8"}},
+		{`{"name":"API","limits":{}}`, []string{`/name: does not match pattern "^[a-z]+$"`}},
+		{`{"name":"api","limits":{},"tags":[]}`, []string{"/tags: 0 items < minItems 1"}},
+		{`{"name":"api","limits":{},"tags":["a",2,3]}`, []string{"/tags: 3 items > maxItems 2", "/tags/1: expected string", "/tags/2: expected string"}},
+		{`{"name":"api","limits":{},"mode":"maybe"}`, []string{"/mode: not in enum"}},
+		{`{"name":"api","limits":{},"ratio":true}`, []string{"/ratio: expected number"}},
+		{`{"name":"api","limits":{},"extra":0}`, []string{"/extra: expected null"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got []string
+			for _, p := range checkVar(tt.value, rule, nil) {
+				if p.Code != CodeJSONSchema || p.Field != "json_schema" {
+					t.Errorf("unexpected problem %+v", p)
+				}
+				got = append(got, p.Message)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Values that are not json are reported by the type check alone.
+	if p := ValidateVar("{", rule); len(p) != 1 || p[0] != "not valid json" {
+		t.Errorf("expected only the type problem, got %v", p)
+	}
+}
+
+func TestCheckJSONSchema_DoesNotEchoValues(t *testing.T) {
+	rule := Rule{Type: TypeJSON, JSONSchema: &JSONSchema{
+		Type:  "array",
+		Items: &JSONSchema{Type: "integer"},
+	}}
+	value := `["s3cr3t-token"` + strings.Repeat(`,"x"`, 20) + `]`
+	problems := checkVar(value, rule, nil)
+	if len(problems) != maxSchemaViolations {
+		t.Errorf("expected %d problems, got %d", maxSchemaViolations, len(problems))
+	}
+	for _, p := range problems {
+		if strings.Contains(p.Message, "s3cr3t") {
+			t.Errorf("problem echoes the value: %s", p.Message)
+		}
+	}
+}
+
+func TestLoadRuleSetFile_JSONSchemaFile(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/schema/rules.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := rs.Rules[0]
+	if p := ValidateVar(`{"limits":{"rps":"fast"}}`, rule); len(p) != 1 || p[0] != "/limits/rps: expected integer" {
+		t.Errorf("expected schema file to apply, got %v", p)
+	}
+
+	if err := rs.ApplyProfile("strict"); err != nil {
+		t.Fatal(err)
+	}
+	if p := ValidateVar(`{"limits":{}}`, rs.Rules[0]); len(p) != 1 || p[0] != `(root): missing required property "owner"` {
+		t.Errorf("expected profile schema file to apply, got %v", p)
+	}
+}
+
+func TestLoadRuleSet_JSONSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"file without loader", "rules:\n  - key: A\n    type: json\n    json_schema: a.json\n", "schema files require LoadRuleSetFile or LoadRuleSetFS"},
+		{"not json", "rules:\n  - key: A\n    type: string\n    json_schema: {type: object}\n", "rule[0] (A): json_schema requires type json"},
+		{"unknown type", "rules:\n  - key: A\n    type: json\n    json_schema: {properties: {n: {type: int}}}\n", `rule[0] (A): json_schema /properties/n: unknown type "int"`},
+		{"bad pattern", "rules:\n  - key: A\n    type: json\n    json_schema: {pattern: \"[\"}\n", "json_schema: invalid pattern"},
+		{"min > max", "rules:\n  - key: A\n    type: json\n    json_schema: {items: {minimum: 2, maximum: 1}}\n", "json_schema /items: minimum > maximum"},
+		{"minLength > maxLength", "rules:\n  - key: A\n    type: json\n    json_schema: {minLength: 2, maxLength: 1}\n", "minLength > maxLength"},
+		{"negative minItems", "rules:\n  - key: A\n    type: json\n    json_schema: {minItems: -1}\n", "minItems and maxItems must be >= 0"},
+		{"nested file", "rules:\n  - key: A\n    type: json\n    json_schema: {items: a.json}\n", "json_schema /items: schema files are only allowed at the top level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRuleSet([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
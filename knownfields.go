@@ -0,0 +1,184 @@
+package envdoc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleFileTypes names the structs of a rules file in unknown field errors.
+var ruleFileTypes = map[reflect.Type]string{
+	reflect.TypeFor[RuleSet]():    "rules file",
+	reflect.TypeFor[Rule]():       "rule",
+	reflect.TypeFor[Condition]():  "condition",
+	reflect.TypeFor[Comparison](): "comparison",
+	reflect.TypeFor[Group]():      "group",
+	reflect.TypeFor[Assertion]():  "assertion",
+}
+
+// decodeYAMLStrict decodes YAML (or JSON) data into v, rejecting mapping
+// keys that v's structs have no field for. Empty data decodes to nothing.
+func decodeYAMLStrict(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return explainUnknownFields(err)
+}
+
+// unknownFieldRe matches the yaml.v3 error for a key with no struct field.
+var unknownFieldRe = regexp.MustCompile(`^line (\d+): field (.+) not found in type (\S+)$`)
+
+// explainUnknownFields rewrites the unknown field errors in err to name the
+// rules file struct and suggest the nearest valid field.
+func explainUnknownFields(err error) error {
+	var te *yaml.TypeError
+	if !errors.As(err, &te) {
+		return err
+	}
+	msgs := make([]string, len(te.Errors))
+	for i, msg := range te.Errors {
+		msgs[i] = msg
+		m := unknownFieldRe.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		for t := range ruleFileTypes {
+			if t.String() == m[3] {
+				msgs[i] = fmt.Sprintf("line %s: %s", m[1], unknownField(t, m[2]))
+				break
+			}
+		}
+	}
+	return &yaml.TypeError{Errors: msgs}
+}
+
+// unknownField describes name as a field t does not have, suggesting the
+// nearest one t has.
+func unknownField(t reflect.Type, name string) string {
+	label, ok := ruleFileTypes[t]
+	if !ok {
+		label = t.Name()
+	}
+	msg := fmt.Sprintf("unknown field %q in %s", name, label)
+	if near := nearestField(name, yamlFields(t)); near != "" {
+		msg += fmt.Sprintf("; did you mean %q?", near)
+	}
+	return msg
+}
+
+// yamlFields returns the YAML names of t's fields.
+func yamlFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		if name := yamlName(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// yamlName returns the key f is decoded from, or "" if it is not decoded.
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// nearestField returns the field closest to name by edit distance, ignoring
+// case, or "" if none is close enough to be a likely typo.
+func nearestField(name string, fields []string) string {
+	best, bestDist := "", max(1, len(name)/3)+1
+	for _, f := range fields {
+		if d := editDistance(strings.ToLower(name), f); d < bestDist {
+			best, bestDist = f, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkKnownFields does for a decoded node tree, such as a TOML document,
+// what the KnownFields decoder option does for YAML: it reports, with its
+// line, every mapping key that the matching struct in t has no field for.
+// Types with their own UnmarshalYAML check their keys themselves.
+func checkKnownFields(node *yaml.Node, t reflect.Type) error {
+	var msgs []string
+	var walk func(n *yaml.Node, t reflect.Type)
+	walk = func(n *yaml.Node, t reflect.Type) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if reflect.PointerTo(t).Implements(reflect.TypeFor[yaml.Unmarshaler]()) {
+			return
+		}
+		switch {
+		case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+			fields := make(map[string]reflect.Type)
+			for i := range t.NumField() {
+				f := t.Field(i)
+				if name := yamlName(f); name != "" {
+					fields[name] = f.Type
+				}
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				ft, ok := fields[k.Value]
+				if !ok {
+					msgs = append(msgs, fmt.Sprintf("line %d: %s", k.Line, unknownField(t, k.Value)))
+					continue
+				}
+				walk(n.Content[i+1], ft)
+			}
+		case t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c, t.Elem())
+			}
+		case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i], t.Elem())
+			}
+		}
+	}
+	walk(node, t)
+	if len(msgs) > 0 {
+		return &yaml.TypeError{Errors: msgs}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package envdoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRuleSet_UnknownFields(t *testing.T) {
+	_, err := LoadRuleSet([]byte(`
+rulez: []
+rules:
+  - key: A
+    minlen: 3
+    required_if:
+      - key: B
+        equal: x
+    xyzzy: true
+profiles:
+  dev:
+    - key: A
+      MaxLen: 2
+      required_if:
+        - key: B
+          equalz: x
+override:
+  - key: A
+    compare:
+      - op: "<"
+        kee: B
+`))
+	if err == nil {
+		t.Fatal("expected unknown field errors")
+	}
+	for _, want := range []string{
+		`line 2: unknown field "rulez" in rules file; did you mean "rules"?`,
+		`line 5: unknown field "minlen" in rule; did you mean "min_len"?`,
+		`line 8: unknown field "equal" in condition; did you mean "equals"?`,
+		`line 9: unknown field "xyzzy" in rule` + "\n",
+		`line 13: unknown field "MaxLen" in rule; did you mean "max_len"?`,
+		`line 16: unknown field "equalz" in condition; did you mean "equals"?`,
+		`line 21: unknown field "kee" in comparison; did you mean "key"?`,
+	} {
+		if !strings.Contains(err.Error()+"\n", want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadRuleSet_SchemaKey(t *testing.T) {
+	rs, err := LoadRuleSet([]byte(`{"$schema": "./rules.schema.json", "rules": [{"key": "A"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 1 {
+		t.Errorf("unexpected rule set %+v", rs)
+	}
+	if _, err := LoadRuleSet(nil); err != nil {
+		t.Errorf("expected empty rules to load, got %v", err)
+	}
+}
+
+func TestNearestField(t *testing.T) {
+	fields := yamlFields(reflect.TypeFor[Rule]())
+	tests := []struct{ name, want string }{
+		{"minlen", "min_len"},
+		{"requried", "required"},
+		{"Type", "type"},
+		{"item_typ", "item_type"},
+		{"maxLength", ""},
+		{"x", ""},
+	}
+	for _, tt := range tests {
+		if got := nearestField(tt.name, fields); got != tt.want {
+			t.Errorf("nearestField(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
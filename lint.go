@@ -0,0 +1,169 @@
+package envdoc
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+)
+
+// FindingCode is a stable, machine-readable lint finding identifier.
+type FindingCode string
+
+const (
+	LintAllowedInvalid     FindingCode = "LINT_ALLOWED_INVALID"
+	LintDuplicateAllowed   FindingCode = "LINT_DUPLICATE_ALLOWED"
+	LintUnsatisfiable      FindingCode = "LINT_UNSATISFIABLE"
+	LintRegexNeverMatches  FindingCode = "LINT_REGEX_NEVER_MATCHES"
+	LintSecretFingerprint  FindingCode = "LINT_SECRET_FINGERPRINT"
+	LintDeprecatedRequired FindingCode = "LINT_DEPRECATED_REQUIRED"
+	LintSecretExample      FindingCode = "LINT_SECRET_EXAMPLE"
+)
+
+// Finding is a constraint LintRules flagged in a rule. Index is the rule's
+// position in the rules passed in and Rule its key or pattern; Field names
+// the rule field at fault.
+type Finding struct {
+	Code     FindingCode `json:"code"`
+	Index    int         `json:"index"`
+	Rule     string      `json:"rule"`
+	Field    string      `json:"field"`
+	Severity Severity    `json:"severity"`
+	Message  string      `json:"message"`
+}
+
+// String renders the finding as "rule[N] (KEY): severity CODE: message".
+func (f Finding) String() string {
+	return fmt.Sprintf("rule[%d] (%s): %s %s: %s", f.Index, f.Rule, f.Severity, f.Code, f.Message)
+}
+
+// LintRules looks for constraints that contradict each other or can never
+// be met, which validation accepts because each is well-formed on its own.
+// Errors mark rules no value can satisfy; warnings mark dead or risky
+// settings. Findings are returned in rule order. A fingerprinted secret is
+// not flagged when its rule gives a fingerprint_note.
+func LintRules(rules []Rule) []Finding {
+	var findings []Finding
+	for idx, r := range rules {
+		add := func(code FindingCode, field string, sev Severity, format string, args ...any) {
+			findings = append(findings, Finding{
+				Code: code, Index: idx, Rule: r.name(), Field: field, Severity: sev,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		// For lists and maps, value constraints describe the elements.
+		vr := r
+		if r.isCollection() {
+			vr = r.elementRule()
+		}
+
+		var re *syntax.Regexp
+		if vr.Regex != "" {
+			if parsed, err := syntax.Parse(vr.Regex, syntax.Perl); err == nil {
+				re = parsed.Simplify()
+			}
+		}
+		if re != nil && neverMatches(re) {
+			add(LintRegexNeverMatches, "regex", SeverityError, "regex %q can never match", vr.Regex)
+		} else if re != nil && !r.isCollection() && r.MaxLen != nil && minMatchLen(re) > *r.MaxLen {
+			add(LintUnsatisfiable, "max_len", SeverityError, "regex %q needs at least %d characters but max_len is %d", vr.Regex, minMatchLen(re), *r.MaxLen)
+		}
+
+		if len(vr.Allowed) > 0 {
+			others := vr
+			others.Allowed = nil
+			seen := make(map[string]bool)
+			dead := 0
+			for _, a := range vr.Allowed {
+				if seen[a] {
+					add(LintDuplicateAllowed, "allowed", SeverityWarning, "allowed value %q is listed more than once", a)
+					continue
+				}
+				seen[a] = true
+				if problems := checkVar(a, others, nil); len(problems) > 0 {
+					dead++
+					add(LintAllowedInvalid, "allowed", SeverityWarning, "allowed value %q can never be valid: %s", a, strings.Join(problemMessages(problems), "; "))
+				}
+			}
+			if dead == len(seen) {
+				add(LintUnsatisfiable, "allowed", SeverityError, "no allowed value passes the rule's other checks")
+			}
+		}
+
+		if r.Secret != nil && *r.Secret && r.Fingerprint != nil && *r.Fingerprint && r.FingerprintNote == "" {
+			add(LintSecretFingerprint, "fingerprint", SeverityWarning, "fingerprint is enabled on a secret; short or guessable secrets can be recovered from it")
+		}
+		if r.Example != "" && classifySecretLike(r.name(), r) {
+			add(LintSecretExample, "example", SeverityWarning, "example on a secret-like variable; generated docs never show it, and a real secret here would be committed")
+		}
+		if r.Deprecated && r.Required {
+			add(LintDeprecatedRequired, "deprecated", SeverityWarning, "required and deprecated; drop required so the variable can be removed")
+		}
+	}
+	return findings
+}
+
+// neverMatches reports whether re matches no string at all, e.g. an empty
+// character class or text after the end anchor ("a$b").
+func neverMatches(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return true
+	case syntax.OpCharClass:
+		return len(re.Rune) == 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return neverMatches(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min > 0 && neverMatches(re.Sub[0])
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !neverMatches(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpConcat:
+		for i, sub := range re.Sub {
+			if neverMatches(sub) {
+				return true
+			}
+			if sub.Op == syntax.OpEndText && minMatchLen(&syntax.Regexp{Op: syntax.OpConcat, Sub: re.Sub[i+1:]}) > 0 {
+				return true
+			}
+			if sub.Op == syntax.OpBeginText && minMatchLen(&syntax.Regexp{Op: syntax.OpConcat, Sub: re.Sub[:i]}) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// minMatchLen returns the length in characters of the shortest string re
+// can match.
+func minMatchLen(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpPlus:
+		return minMatchLen(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * minMatchLen(re.Sub[0])
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += minMatchLen(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := -1
+		for _, sub := range re.Sub {
+			if m := minMatchLen(sub); n < 0 || m < n {
+				n = m
+			}
+		}
+		return max(n, 0)
+	}
+	return 0
+}
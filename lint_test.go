@@ -0,0 +1,65 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	boolTrue := true
+	tests := []struct {
+		name string
+		rule Rule
+		want []string
+	}{
+		{"clean", Rule{Key: "MODE", Regex: "^[a-z]+$", Allowed: []string{"dev", "prod"}, MaxLen: intPtr(4)}, nil},
+		{"type mismatch", Rule{Key: "FLAG", Type: TypeInt, Allowed: []string{"yes", "no"}}, []string{
+			`warning LINT_ALLOWED_INVALID: allowed value "yes" can never be valid: not a valid int`,
+			`warning LINT_ALLOWED_INVALID: allowed value "no" can never be valid: not a valid int`,
+			"error LINT_UNSATISFIABLE: no allowed value passes the rule's other checks",
+		}},
+		{"regex excludes one", Rule{Key: "MODE", Regex: "^[a-z]+$", Allowed: []string{"dev", "Prod"}}, []string{
+			`warning LINT_ALLOWED_INVALID: allowed value "Prod" can never be valid: does not match regex "^[a-z]+$"`,
+		}},
+		{"max_len below allowed", Rule{Key: "REGION", MaxLen: intPtr(2), Allowed: []string{"eu-west", "us-east"}}, []string{
+			`warning LINT_ALLOWED_INVALID: allowed value "eu-west" can never be valid: length 7 > max_len 2`,
+			`warning LINT_ALLOWED_INVALID: allowed value "us-east" can never be valid: length 7 > max_len 2`,
+			"error LINT_UNSATISFIABLE: no allowed value passes the rule's other checks",
+		}},
+		{"list items", Rule{Key: "PORTS", Type: TypeList, ItemType: TypePort, Max: "1024", Allowed: []string{"80", "8080", "80"}}, []string{
+			`warning LINT_ALLOWED_INVALID: allowed value "8080" can never be valid: value above maximum 1024`,
+			`warning LINT_DUPLICATE_ALLOWED: allowed value "80" is listed more than once`,
+		}},
+		{"anchored text", Rule{Key: "ID", Regex: "^id$-[0-9]+"}, []string{`error LINT_REGEX_NEVER_MATCHES: regex "^id$-[0-9]+" can never match`}},
+		{"empty class", Rule{Key: "ID", Regex: `[^\x00-\x{10FFFF}]`}, []string{`error LINT_REGEX_NEVER_MATCHES: regex "[^\\x00-\\x{10FFFF}]" can never match`}},
+		{"one dead alternative", Rule{Key: "ID", Regex: `x|[^\x00-\x{10FFFF}]y`}, nil},
+		{"no alternative", Rule{Key: "ID", Regex: `a^b|c$d`}, []string{`error LINT_REGEX_NEVER_MATCHES: regex "a^b|c$d" can never match`}},
+		{"regex too long", Rule{Key: "TOKEN", Regex: "^tok_[a-z0-9]{16}$", MaxLen: intPtr(12)}, []string{
+			`error LINT_UNSATISFIABLE: regex "^tok_[a-z0-9]{16}$" needs at least 20 characters but max_len is 12`,
+		}},
+		{"secret fingerprint", Rule{Key: "API_KEY", Secret: &boolTrue, Fingerprint: &boolTrue}, []string{
+			"warning LINT_SECRET_FINGERPRINT: fingerprint is enabled on a secret; short or guessable secrets can be recovered from it",
+		}},
+		{"secret fingerprint with note", Rule{Key: "API_KEY", Secret: &boolTrue, Fingerprint: &boolTrue, FingerprintNote: "random 32-byte token"}, nil},
+		{"secret example", Rule{Key: "DB_PASSWORD", Example: "hunter2"}, []string{
+			"warning LINT_SECRET_EXAMPLE: example on a secret-like variable; generated docs never show it, and a real secret here would be committed",
+		}},
+		{"deprecated required", Rule{Key: "OLD", Required: true, Deprecated: true}, []string{
+			"warning LINT_DEPRECATED_REQUIRED: required and deprecated; drop required so the variable can be removed",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range LintRules([]Rule{{Key: "OTHER"}, tt.rule}) {
+				if f.Index != 1 || f.Rule != tt.rule.Key {
+					t.Errorf("finding on the wrong rule: %+v", f)
+				}
+				got = append(got, strings.TrimPrefix(f.String(), "rule[1] ("+tt.rule.Key+"): "))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package envdoc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulePatch changes fields of an existing rule. It is written like a rule:
+// key (or key_pattern/key_regex) selects the target and every other field
+// present replaces the target's value.
+type RulePatch struct {
+	Target string
+	node   yaml.Node
+}
+
+// UnmarshalYAML records the patch's fields as written.
+func (p *RulePatch) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: rule patch must be a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "key", "key_pattern", "key_regex":
+			if p.Target == "" {
+				p.Target = node.Content[i+1].Value
+			}
+		}
+	}
+	if p.Target == "" {
+		return fmt.Errorf("line %d: rule patch needs key, key_pattern or key_regex", node.Line)
+	}
+	// Unknown fields, also in nested conditions and comparisons, are reported
+	// as a TypeError so the decoder lists them with the rest of the file's.
+	if err := checkKnownFields(node, reflect.TypeFor[Rule]()); err != nil {
+		return err
+	}
+	p.node = *node
+	return nil
+}
+
+// MarshalYAML writes the patch back as it was read.
+func (p RulePatch) MarshalYAML() (any, error) {
+	return &p.node, nil
+}
+
+// apply returns a copy of r with the patch's fields replaced. The rule is
+// round-tripped through YAML so the result shares no pointers with r.
+func (p RulePatch) apply(r Rule) (Rule, error) {
+	var base yaml.Node
+	if err := base.Encode(r); err != nil {
+		return r, err
+	}
+	for i := 0; i+1 < len(p.node.Content); i += 2 {
+		k, v := p.node.Content[i], p.node.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value == k.Value {
+				base.Content[j+1] = v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			base.Content = append(base.Content, k, v)
+		}
+	}
+	var out Rule
+	if err := base.Decode(&out); err != nil {
+		return r, err
+	}
+	out.source = r.source
+	return out, nil
+}
+
+// applyPatches applies each patch to the rule it targets. label describes
+// where the patches come from for error messages.
+func applyPatches(rules []Rule, patches []RulePatch, label string) error {
+	for _, p := range patches {
+		idx := -1
+		for i, r := range rules {
+			if r.name() == p.Target {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("%s: no rule %q to override", label, p.Target)
+		}
+		patched, err := p.apply(rules[idx])
+		if err != nil {
+			return fmt.Errorf("%s: rule %q: %w", label, p.Target, err)
+		}
+		rules[idx] = patched
+	}
+	return nil
+}
+
+// loader reads rule files and resolves their includes. read loads a file
+// by name and join resolves an include relative to the including file.
+// A nil read means includes are not available. declared records each
+// file's own rules as written, to tell overridden copies from plain ones.
+type loader struct {
+	read     func(name string) ([]byte, error)
+	join     func(from, include string) string
+	stack    []string
+	declared map[string][]Rule
+}
+
+// newOSLoader returns a loader for the OS filesystem.
+func newOSLoader() *loader {
+	return &loader{
+		read: readFile,
+		join: func(from, include string) string {
+			if filepath.IsAbs(include) {
+				return filepath.Clean(include)
+			}
+			return filepath.Join(filepath.Dir(from), include)
+		},
+	}
+}
+
+// newFSLoader returns a loader for fsys, e.g. an embed.FS.
+func newFSLoader(fsys fs.FS) *loader {
+	return &loader{
+		read: func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) },
+		join: func(from, include string) string {
+			return path.Join(path.Dir(from), include)
+		},
+	}
+}
+
+// loadFile reads, parses and resolves the rules file at name.
+func (l *loader) loadFile(name string) (*RuleSet, error) {
+	for i, s := range l.stack {
+		if s == name {
+			cycle := append(append([]string(nil), l.stack[i:]...), name)
+			return nil, fmt.Errorf("envdoc: include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	data, err := l.read(name)
+	if err != nil {
+		return nil, fmt.Errorf("envdoc: reading rules file: %w", err)
+	}
+	var rs RuleSet
+	if strings.EqualFold(path.Ext(name), ".toml") {
+		err = decodeTOML(data, &rs)
+	} else {
+		err = decodeYAMLStrict(data, &rs)
+	}
+	if err != nil {
+		return nil, positionError(name, err)
+	}
+	return l.resolve(&rs, name)
+}
+
+// decodeTOML parses TOML data into v through the YAML decoder. As with
+// YAML, keys that v's structs have no field for are errors.
+func decodeTOML(data []byte, v any) error {
+	node, err := parseTOML(data)
+	if err != nil {
+		return err
+	}
+	if err := checkKnownFields(node, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	return node.Decode(v)
+}
+
+// lineErrorRe matches the "line N: message" errors of the YAML and TOML
+// decoders.
+var lineErrorRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// positionError rewrites a decoding error of the rules file name into
+// "name:line: message" form, one line per error.
+func positionError(name string, err error) error {
+	msgs := []string{err.Error()}
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		msgs = append([]string(nil), te.Errors...)
+	}
+	for i, msg := range msgs {
+		if m := lineErrorRe.FindStringSubmatch(msg); m != nil {
+			msgs[i] = fmt.Sprintf("%s:%s: %s", name, m[1], m[2])
+		} else {
+			msgs[i] = fmt.Sprintf("%s: %s", name, strings.TrimPrefix(msg, "yaml: "))
+		}
+	}
+	return fmt.Errorf("envdoc: parsing rules file %s", strings.Join(msgs, "\n  "))
+}
+
+// resolve merges the includes of rs (loaded from name, which may be empty)
+// into a single RuleSet. Included rules come first, in include order, then
+// rs's overrides are applied to them, then rs's own rules are appended.
+// Patches for the same profile are concatenated across files.
+// Every include is resolved on its own, so each includer can override the
+// rules of a shared fragment; copies of the fragment reached through
+// several includes are merged once (see mergeIncluded).
+func (l *loader) resolve(rs *RuleSet, name string) (*RuleSet, error) {
+	if name != "" {
+		l.stack = append(l.stack, name)
+		defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	}
+
+	if err := l.loadSchemas(rs, name); err != nil {
+		return nil, err
+	}
+	if name != "" {
+		if l.declared == nil {
+			l.declared = make(map[string][]Rule)
+		}
+		own := make([]Rule, len(rs.Rules))
+		for i, r := range rs.Rules {
+			r.source = name
+			own[i] = r
+		}
+		l.declared[name] = own
+	}
+
+	merged := &RuleSet{}
+	for _, inc := range rs.Include {
+		if l.read == nil {
+			return nil, fmt.Errorf("envdoc: include %q: includes require LoadRuleSetFile or LoadRuleSetFS", inc)
+		}
+		incName := inc
+		if name != "" {
+			incName = l.join(name, inc)
+		}
+		sub, err := l.loadFile(incName)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.mergeIncluded(merged, sub, name); err != nil {
+			return nil, err
+		}
+	}
+
+	label := "envdoc: override"
+	if name != "" {
+		label = fmt.Sprintf("envdoc: %s: override", name)
+	}
+	if err := applyPatches(merged.Rules, rs.Override, label); err != nil {
+		return nil, err
+	}
+
+	for _, r := range rs.Rules {
+		r.source = name
+		merged.Rules = append(merged.Rules, r)
+	}
+	merged.Groups = append(merged.Groups, rs.Groups...)
+	merged.Assertions = append(merged.Assertions, rs.Assertions...)
+	mergeProfiles(merged, rs.Profiles)
+	return merged, nil
+}
+
+// mergeIncluded merges the resolved include sub into merged for the file
+// name. A rule that is already in merged because another include reached
+// the same file is merged once: an overridden copy replaces a plain one, a
+// plain copy is dropped, and two copies overridden differently are an
+// error. Groups, assertions and profile patches that are already in merged
+// are dropped too.
+func (l *loader) mergeIncluded(merged, sub *RuleSet, name string) error {
+	for _, r := range sub.Rules {
+		idx := slices.IndexFunc(merged.Rules, func(m Rule) bool {
+			return m.source != "" && m.source == r.source && m.name() == r.name()
+		})
+		if idx < 0 {
+			merged.Rules = append(merged.Rules, r)
+			continue
+		}
+		existing, declared := merged.Rules[idx], l.declaredRule(r)
+		switch {
+		case reflect.DeepEqual(existing, r) || reflect.DeepEqual(r, declared):
+		case reflect.DeepEqual(existing, declared):
+			merged.Rules[idx] = r
+		default:
+			return fmt.Errorf("envdoc: %s: rule %q from %s is overridden differently by two includes", name, r.name(), r.source)
+		}
+	}
+	for _, g := range sub.Groups {
+		if !slices.ContainsFunc(merged.Groups, func(m Group) bool { return reflect.DeepEqual(m, g) }) {
+			merged.Groups = append(merged.Groups, g)
+		}
+	}
+	for _, a := range sub.Assertions {
+		if !slices.Contains(merged.Assertions, a) {
+			merged.Assertions = append(merged.Assertions, a)
+		}
+	}
+	mergeProfiles(merged, sub.Profiles)
+	return nil
+}
+
+// declaredRule returns r as written in its source file.
+func (l *loader) declaredRule(r Rule) Rule {
+	for _, d := range l.declared[r.source] {
+		if d.name() == r.name() {
+			return d
+		}
+	}
+	return Rule{}
+}
+
+// mergeProfiles appends each profile's patches to the same profile in rs,
+// skipping patches the profile already has.
+func mergeProfiles(rs *RuleSet, profiles map[string][]RulePatch) {
+	for name, patches := range profiles {
+		if rs.Profiles == nil {
+			rs.Profiles = make(map[string][]RulePatch)
+		}
+		for _, p := range patches {
+			if !slices.ContainsFunc(rs.Profiles[name], func(m RulePatch) bool { return reflect.DeepEqual(m, p) }) {
+				rs.Profiles[name] = append(rs.Profiles[name], p)
+			}
+		}
+	}
+}
+
+// loadSchemas reads the json_schema files named by rs's rules, overrides
+// and profiles, relative to name.
+func (l *loader) loadSchemas(rs *RuleSet, name string) error {
+	for i, r := range rs.Rules {
+		if r.JSONSchema == nil || r.JSONSchema.file == "" {
+			continue
+		}
+		node, err := l.readSchema(r.JSONSchema.file, name)
+		if err != nil {
+			return fmt.Errorf("envdoc: rule %q: %w", r.name(), err)
+		}
+		var schema JSONSchema
+		if err := node.Decode(&schema); err != nil {
+			return fmt.Errorf("envdoc: rule %q: json_schema %s: %w", r.name(), r.JSONSchema.file, err)
+		}
+		rs.Rules[i].JSONSchema = &schema
+	}
+
+	patches := [][]RulePatch{rs.Override}
+	for _, p := range rs.Profiles {
+		patches = append(patches, p)
+	}
+	for _, list := range patches {
+		for i := range list {
+			content := list[i].node.Content
+			for j := 0; j+1 < len(content); j += 2 {
+				if content[j].Value != "json_schema" || content[j+1].Kind != yaml.ScalarNode {
+					continue
+				}
+				node, err := l.readSchema(content[j+1].Value, name)
+				if err != nil {
+					return fmt.Errorf("envdoc: patch %q: %w", list[i].Target, err)
+				}
+				content[j+1] = node
+			}
+		}
+	}
+	return nil
+}
+
+// readSchema reads and parses the schema file at file, relative to from.
+func (l *loader) readSchema(file, from string) (*yaml.Node, error) {
+	if l.read == nil {
+		return nil, fmt.Errorf("json_schema %s: schema files require LoadRuleSetFile or LoadRuleSetFS", file)
+	}
+	if from != "" {
+		file = l.join(from, file)
+	}
+	data, err := l.read(file)
+	if err != nil {
+		return nil, fmt.Errorf("json_schema: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("json_schema %s: %w", file, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("json_schema %s: schema must be an object", file)
+	}
+	return doc.Content[0], nil
+}
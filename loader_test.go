@@ -0,0 +1,294 @@
+package envdoc
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadRuleSetFile_Include(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/include/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range rs.Rules {
+		keys = append(keys, r.Key)
+	}
+	// db.yaml is included twice (directly and via redis.yaml) but merged once.
+	want := "DB_HOST,DB_PORT,DB_PASSWORD,REDIS_ADDR,APP_URL"
+	if strings.Join(keys, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(keys, ","))
+	}
+	if len(rs.Groups) != 1 {
+		t.Errorf("expected 1 group, got %d", len(rs.Groups))
+	}
+
+	port := rs.Rules[1]
+	if port.Required {
+		t.Error("expected override to make DB_PORT optional")
+	}
+	if port.Max != "6000" || port.Type != TypePort {
+		t.Errorf("expected override to add max and keep type, got %+v", port)
+	}
+	if pw := rs.Rules[2]; pw.Secret == nil || !*pw.Secret || pw.MinLen == nil || *pw.MinLen != 16 {
+		t.Errorf("expected DB_PASSWORD untouched, got %+v", pw)
+	}
+}
+
+func TestLoadRuleSetFile_IncludeSharedOverride(t *testing.T) {
+	// a.yaml and b.yaml both include db.yaml; b.yaml overrides DB_HOST.
+	rs, err := LoadRuleSetFile("testdata/include/diamond/svc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range rs.Rules {
+		keys = append(keys, r.Key)
+	}
+	if want := "DB_HOST,DB_PORT,DB_PASSWORD,A_URL,B_URL"; strings.Join(keys, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(keys, ","))
+	}
+	if rs.Rules[0].Type != TypeString {
+		t.Errorf("expected b.yaml's override of DB_HOST, got %+v", rs.Rules[0])
+	}
+
+	_, err = LoadRuleSetFile("testdata/include/diamond/conflict.yaml")
+	if err == nil || !strings.Contains(err.Error(), `rule "DB_HOST" from testdata/include/shared/db.yaml is overridden differently by two includes`) {
+		t.Errorf("expected conflicting override error, got %v", err)
+	}
+}
+
+func TestLoadRuleSetFile_IncludeDuplicate(t *testing.T) {
+	_, err := LoadRuleSetFile("testdata/include/duplicate.yaml")
+	if err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `duplicate key "DB_HOST"`) ||
+		!strings.Contains(msg, "testdata/include/shared/db.yaml") ||
+		!strings.Contains(msg, "testdata/include/duplicate.yaml") {
+		t.Errorf("expected error naming both files, got: %v", err)
+	}
+}
+
+func TestLoadRuleSetFile_IncludeCycle(t *testing.T) {
+	_, err := LoadRuleSetFile("testdata/include/cycle_a.yaml")
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("expected include cycle error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "cycle_a.yaml -> testdata/include/cycle_b.yaml -> testdata/include/cycle_a.yaml") {
+		t.Errorf("expected cycle path in error, got: %v", err)
+	}
+}
+
+func TestLoadRuleSetFile_OverrideUnknownRule(t *testing.T) {
+	_, err := LoadRuleSetFile("testdata/include/bad_override.yaml")
+	if err == nil || !strings.Contains(err.Error(), `no rule "MISSING" to override`) {
+		t.Fatalf("expected override error, got %v", err)
+	}
+}
+
+func TestLoadRuleSet_IncludeWithoutFile(t *testing.T) {
+	_, err := LoadRuleSet([]byte("include: [db.yaml]\nrules: []\n"))
+	if err == nil || !strings.Contains(err.Error(), "includes require") {
+		t.Fatalf("expected include error, got %v", err)
+	}
+}
+
+func TestLoadRuleSetFS(t *testing.T) {
+	db, err := os.ReadFile("testdata/include/shared/db.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"rules/shared/db.yaml": {Data: db},
+		"rules/app.yaml": {Data: []byte(`
+include: [shared/db.yaml]
+override:
+  - key: DB_HOST
+    type: string
+rules:
+  - key: APP_URL
+    type: url
+`)},
+	}
+	rs, err := LoadRuleSetFS(fsys, "rules/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 4 {
+		t.Fatalf("expected 4 rules, got %d", len(rs.Rules))
+	}
+	if rs.Rules[0].Type != TypeString {
+		t.Errorf("expected overridden type, got %s", rs.Rules[0].Type)
+	}
+}
+
+func TestRulePatch_DoesNotShareState(t *testing.T) {
+	orig := Rule{Key: "A", MinLen: intPtr(4)}
+	var p RulePatch
+	if err := yaml.Unmarshal([]byte("key: A\nmin_len: 8\n"), &p); err != nil {
+		t.Fatal(err)
+	}
+	patched, err := p.apply(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *patched.MinLen != 8 || *orig.MinLen != 4 {
+		t.Errorf("expected independent copies, got patched=%d orig=%d", *patched.MinLen, *orig.MinLen)
+	}
+}
+
+func TestRuleSet_ApplyProfile(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/profiles/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if rs.ActiveProfile != "prod" {
+		t.Errorf("expected active profile prod, got %q", rs.ActiveProfile)
+	}
+	// The included file's prod patches apply alongside the including file's.
+	level, db, cert := rs.Rules[0], rs.Rules[1], rs.Rules[2]
+	if strings.Join(level.Allowed, ",") != "info,warn,error" {
+		t.Errorf("expected included prod patch on LOG_LEVEL, got %v", level.Allowed)
+	}
+	if !db.Required || db.Type != TypeURL || !cert.Required {
+		t.Errorf("expected prod patches applied, got %+v %+v", db, cert)
+	}
+
+	report := inspect(MapEnvReader{}, fixedClock{}, *rs, Config{}, nil)
+	if report.Profile != "prod" {
+		t.Errorf("expected report profile prod, got %q", report.Profile)
+	}
+	if report.Summary.Missing != 2 {
+		t.Errorf("expected 2 missing, got %d", report.Summary.Missing)
+	}
+}
+
+func TestRuleSet_ApplyProfileErrors(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/profiles/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.ApplyProfile(""); err != nil || rs.ActiveProfile != "" {
+		t.Fatalf("expected empty profile to be a no-op, got %v", err)
+	}
+	if err := rs.ApplyProfile("qa"); err == nil || !strings.Contains(err.Error(), `unknown profile "qa"`) {
+		t.Errorf("expected unknown profile error, got %v", err)
+	}
+	if err := rs.ApplyProfile("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.ApplyProfile("prod"); err == nil || !strings.Contains(err.Error(), "already applied") {
+		t.Errorf("expected already applied error, got %v", err)
+	}
+}
+
+func TestLoadRuleSet_InvalidProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown key", "rules: [{key: A}]\nprofiles:\n  prod: [{key: B, required: true}]\n", `no rule "B" to override`},
+		{"invalid result", "rules: [{key: A, type: int}]\nprofiles:\n  prod: [{key: A, regex: \"[\"}]\n", `profile "prod"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRuleSet([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadRuleSetFile_TOMLAndJSON(t *testing.T) {
+	rs, err := LoadRuleSetFile("testdata/formats/rules.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range rs.Rules {
+		keys = append(keys, r.Key)
+	}
+	if got := strings.Join(keys, ","); got != "DATABASE_URL,DB_HOST,PORT,LOG_LEVEL,OLD_LOG_LEVEL,FEATURE_FLAGS" {
+		t.Fatalf("unexpected rules %s", got)
+	}
+	if r := rs.Rules[0]; r.Type != TypeURL || r.Secret == nil || !*r.Secret {
+		t.Errorf("expected JSON include to load, got %+v", r)
+	}
+	if r := rs.Rules[2]; r.Type != TypePort || !r.Required || r.Min != "1024" {
+		t.Errorf("unexpected PORT rule %+v", r)
+	}
+	if r := rs.Rules[4]; r.RemovalDate != "2026-06-01" || r.ReplacedBy != "LOG_LEVEL" {
+		t.Errorf("expected TOML date as removal_date, got %+v", r)
+	}
+	if s := rs.Rules[5].JSONSchema; s == nil || s.Type != "object" || len(s.Required) != 1 {
+		t.Errorf("expected inline table schema, got %+v", s)
+	}
+	if len(rs.Groups) != 1 || rs.Groups[0].Kind() != GroupOneOf {
+		t.Errorf("expected one_of group, got %+v", rs.Groups)
+	}
+	if err := rs.ApplyProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rs.Rules[3].Allowed; len(got) != 3 {
+		t.Errorf("expected prod profile to narrow allowed, got %v", got)
+	}
+}
+
+func TestLoadRuleSetFile_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"testdata/formats/bad_type.toml", "testdata/formats/bad_type.toml:6: cannot unmarshal !!str `four` into int"},
+		{"testdata/formats/bad_syntax.toml", `testdata/formats/bad_syntax.toml:3: invalid value "port"`},
+		{"testdata/formats/bad_type.json", "testdata/formats/bad_type.json:4: cannot unmarshal !!str `four` into int"},
+		{"testdata/formats/unknown_field.yaml", `testdata/formats/unknown_field.yaml:5: unknown field "requried" in rule; did you mean "required"?`},
+		{"testdata/formats/unknown_field.toml", `testdata/formats/unknown_field.toml:6: unknown field "minlen" in rule; did you mean "min_len"?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := LoadRuleSetFile(tt.file)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadRulesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"rules.toml":  {Data: []byte("include = ['common.yaml']\n\n[[rules]]\nkey = 'APP_URL'\ntype = 'url'\n")},
+		"common.yaml": {Data: []byte("rules:\n  - key: LOG_LEVEL\n")},
+	}
+	rules, err := LoadRulesFS(fsys, "rules.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Key != "LOG_LEVEL" || rules[1].Type != TypeURL {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+}
+
+func TestLoadRuleSetTOML(t *testing.T) {
+	rs, err := LoadRuleSetTOML([]byte("[[rules]]\nkey = \"PORT\"\ntype = \"port\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 1 || rs.Rules[0].Type != TypePort {
+		t.Errorf("unexpected rules %+v", rs.Rules)
+	}
+	if _, err := LoadRuleSetTOML([]byte("[[rules]]\nkey = \"PORT\"\nkey = \"X\"\n")); err == nil || !strings.Contains(err.Error(), `line 3: duplicate key "key"`) {
+		t.Errorf("expected duplicate key error, got %v", err)
+	}
+}
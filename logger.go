@@ -3,30 +3,91 @@ package envdoc
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
-// LogReport writes a one-line-per-variable summary to w.
+// LogReport writes a one-line-per-variable summary to w, followed by one line per group,
+// assertion and pattern rule. The active profile, if any, is logged first. Variable lines carry
+// the highest problem severity and each problem as "severity CODE: message".
 func LogReport(w io.Writer, report *Report) {
+	if report.Profile != "" {
+		fmt.Fprintf(w, "envdoc: profile=%s\n", report.Profile)
+	}
 	for _, r := range report.Results {
 		line := fmt.Sprintf("envdoc: key=%s present=%t", r.Key, r.Present)
 		if r.Present {
 			line += fmt.Sprintf(" len=%d", r.Length)
 		}
+		if r.Defaulted {
+			line += " defaulted=true"
+		}
+		if r.Items != nil {
+			line += fmt.Sprintf(" items=%d", *r.Items)
+		}
+		if len(r.MapKeys) > 0 {
+			line += fmt.Sprintf(" keys=%q", strings.Join(r.MapKeys, ","))
+		}
 		if r.Fingerprint != "" {
 			line += fmt.Sprintf(" fp=%s", r.Fingerprint)
 		}
+		if r.Pattern != "" {
+			line += fmt.Sprintf(" pattern=%q", r.Pattern)
+		}
 		if r.Required {
 			line += fmt.Sprintf(" required=%t", r.Required)
 		}
+		if r.RequiredBy != "" {
+			line += fmt.Sprintf(" required_by=%q", r.RequiredBy)
+		}
 		line += fmt.Sprintf(" valid=%t", r.Valid)
 		if r.Trimmed {
 			line += " trimmed=true"
 		}
-		if len(r.Problems) > 0 {
-			for _, p := range r.Problems {
-				line += fmt.Sprintf(" problem=%q", p)
-			}
+		if r.Deprecated {
+			line += " deprecated=true"
+		}
+		line += logProblems(r.Problems)
+		fmt.Fprintln(w, line)
+	}
+	for _, g := range report.Groups {
+		line := fmt.Sprintf("envdoc: group=%s kind=%s valid=%t", g.Name, g.Kind, g.Valid)
+		line += logProblems(g.Problems)
+		fmt.Fprintln(w, line)
+	}
+	for _, a := range report.Assertions {
+		line := fmt.Sprintf("envdoc: assertion=%s valid=%t", a.Name, a.Valid)
+		if a.Skipped {
+			line += " skipped=true"
 		}
+		line += logProblems(a.Problems)
 		fmt.Fprintln(w, line)
 	}
+	for _, p := range report.Patterns {
+		line := fmt.Sprintf("envdoc: pattern=%q matches=%d valid=%t", p.Pattern, p.Matches, p.Valid)
+		line += logProblems(p.Problems)
+		fmt.Fprintln(w, line)
+	}
+}
+
+// maxSeverity returns the highest severity among problems, or "" if none.
+func maxSeverity(problems []Problem) Severity {
+	var max Severity
+	for _, p := range problems {
+		if max == "" || !max.AtLeast(p.Severity) {
+			max = p.Severity
+		}
+	}
+	return max
+}
+
+// logProblems renders the highest severity and each problem as log fields.
+func logProblems(problems []Problem) string {
+	var out string
+	if sev := maxSeverity(problems); sev != "" {
+		out += " severity=" + string(sev)
+	}
+	for _, p := range problems {
+		out += fmt.Sprintf(" problem=%q", string(p.Severity)+" "+p.String())
+	}
+	return out
 }
@@ -11,9 +11,11 @@ func TestLogReport(t *testing.T) {
 		Results: []VarResult{
 			{Key: "DB_HOST", Present: true, Length: 9, Valid: true},
 			{Key: "DB_PORT", Present: true, Length: 4, Valid: true, Required: true},
-			{Key: "MISSING", Present: false, Valid: false, Required: true, Problems: []string{"required but not set"}},
+			{Key: "MISSING", Present: false, Valid: false, Required: true, Problems: []Problem{{Code: CodeMissing, Field: "required", Severity: SeverityError, Message: "required but not set"}}},
 			{Key: "DB_PASSWORD", Present: true, Length: 32, Valid: true, Fingerprint: "9f2c1a2b", SecretLike: true},
 			{Key: "PADDED", Present: true, Length: 7, Valid: true, Trimmed: true},
+			{Key: "BROKERS", Present: true, Length: 23, Valid: true, Items: intPtr(3)},
+			{Key: "LABELS", Present: true, Length: 15, Valid: true, Items: intPtr(2), MapKeys: []string{"team", "tier"}},
 		},
 	}
 
@@ -22,8 +24,8 @@ func TestLogReport(t *testing.T) {
 	output := buf.String()
 
 	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) != 5 {
-		t.Errorf("expected 5 lines, got %d", len(lines))
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 lines, got %d", len(lines))
 	}
 
 	// Check DB_HOST line
@@ -59,4 +61,44 @@ func TestLogReport(t *testing.T) {
 	if !strings.Contains(lines[4], "trimmed=true") {
 		t.Errorf("expected trimmed=true in line: %s", lines[4])
 	}
+
+	// Check list item count
+	if !strings.Contains(lines[5], "items=3") {
+		t.Errorf("expected items=3 in line: %s", lines[5])
+	}
+
+	// Check map keys
+	if !strings.Contains(lines[6], `keys="team,tier"`) {
+		t.Errorf("expected keys in line: %s", lines[6])
+	}
+}
+
+func TestLogReport_Profile(t *testing.T) {
+	report := &Report{
+		Profile: "prod",
+		Results: []VarResult{{Key: "DB_HOST", Present: true, Valid: true}},
+	}
+
+	var buf bytes.Buffer
+	LogReport(&buf, report)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "envdoc: profile=prod" {
+		t.Errorf("expected profile line first, got %q", lines)
+	}
+}
+
+func TestLogReport_Deprecated(t *testing.T) {
+	report := &Report{
+		Results: []VarResult{{Key: "REDIS_ADDR", Present: true, Valid: true, Deprecated: true,
+			Problems: []Problem{{Code: CodeDeprecated, Field: "deprecated", Severity: SeverityWarning, Message: "deprecated, use CACHE_ADDR instead"}}}},
+	}
+
+	var buf bytes.Buffer
+	LogReport(&buf, report)
+
+	line := buf.String()
+	if !strings.Contains(line, "deprecated=true") || !strings.Contains(line, "severity=warning") || !strings.Contains(line, `problem="warning ENV_DEPRECATED: deprecated, use CACHE_ADDR instead"`) {
+		t.Errorf("expected deprecation in line: %s", line)
+	}
 }
@@ -0,0 +1,134 @@
+package envdoc
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// hostPort is the parsed form of a TypeHostPort value.
+type hostPort struct {
+	Host string
+	Port int64
+}
+
+// parseIP parses an IPv4 or IPv6 address.
+func parseIP(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("not a valid ip address")
+	}
+	return addr, nil
+}
+
+// parseCIDR parses a network prefix such as 10.0.0.0/8, reporting whether
+// the address or the prefix length is the malformed part.
+func parseCIDR(value string) (netip.Prefix, error) {
+	addrPart, bitsPart, ok := strings.Cut(value, "/")
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("cidr: missing prefix length")
+	}
+	addr, err := netip.ParseAddr(addrPart)
+	if err != nil || addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("cidr: invalid address")
+	}
+	bits, err := strconv.Atoi(bitsPart)
+	if err != nil || bits < 0 || bits > addr.BitLen() {
+		return netip.Prefix{}, fmt.Errorf("cidr: invalid prefix length")
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
+
+// parseHostname validates an RFC 1123 hostname. A single trailing dot is
+// accepted for fully-qualified names.
+func parseHostname(value string) (string, error) {
+	name := strings.TrimSuffix(value, ".")
+	if name == "" {
+		return "", fmt.Errorf("hostname: empty")
+	}
+	if len(name) > 253 {
+		return "", fmt.Errorf("hostname: longer than 253 characters")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return "", fmt.Errorf("hostname: empty label")
+		}
+		if len(label) > 63 {
+			return "", fmt.Errorf("hostname: label longer than 63 characters")
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("hostname: label starts or ends with hyphen")
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return "", fmt.Errorf("hostname: invalid character")
+			}
+		}
+	}
+	return value, nil
+}
+
+// parsePort parses a TCP/UDP port number in the range 1-65535.
+func parsePort(value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("port: not a number")
+	}
+	if n < 1 || n > 65535 {
+		return 0, fmt.Errorf("port: out of range 1-65535")
+	}
+	return n, nil
+}
+
+// parseHostPort parses host:port where host is a hostname or an IP address
+// (IPv6 addresses must be bracketed).
+func parseHostPort(value string) (hostPort, error) {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		if !strings.Contains(value, ":") {
+			return hostPort{}, fmt.Errorf("hostport: missing port")
+		}
+		return hostPort{}, fmt.Errorf("hostport: malformed host:port")
+	}
+	if host == "" {
+		return hostPort{}, fmt.Errorf("hostport: missing host")
+	}
+	if _, err := netip.ParseAddr(host); err != nil {
+		if _, err := parseHostname(host); err != nil {
+			return hostPort{}, fmt.Errorf("hostport: invalid host")
+		}
+	}
+	p, err := parsePort(port)
+	if err != nil {
+		return hostPort{}, fmt.Errorf("hostport: invalid port")
+	}
+	return hostPort{Host: host, Port: p}, nil
+}
+
+// checkIPVersion restricts an ip or cidr value to IPv4 (4) or IPv6 (6).
+// It assumes the value already passed the type check.
+func checkIPVersion(value string, typ VarType, version int) error {
+	if version == 0 {
+		return nil
+	}
+	var addr netip.Addr
+	switch typ {
+	case TypeIP:
+		addr, _ = parseIP(value)
+	case TypeCIDR:
+		prefix, _ := parseCIDR(value)
+		addr = prefix.Addr()
+	default:
+		return nil
+	}
+	if version == 4 && !addr.Is4() {
+		return fmt.Errorf("not an IPv4 address")
+	}
+	if version == 6 && !addr.Is6() {
+		return fmt.Errorf("not an IPv6 address")
+	}
+	return nil
+}
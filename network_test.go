@@ -0,0 +1,113 @@
+package envdoc
+
+import "testing"
+
+func TestValidateVar_TypeIP(t *testing.T) {
+	for _, v := range []string{"10.0.0.1", "::1", "fe80::1"} {
+		if problems := ValidateVar(v, Rule{Type: TypeIP}); len(problems) != 0 {
+			t.Errorf("expected no problems for %q, got %v", v, problems)
+		}
+	}
+	problems := ValidateVar("10.0.0.256", Rule{Type: TypeIP})
+	if len(problems) != 1 || problems[0] != "not a valid ip address" {
+		t.Errorf("expected ip problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_IPVersion(t *testing.T) {
+	if problems := ValidateVar("::1", Rule{Type: TypeIP, IPVersion: 4}); len(problems) != 1 {
+		t.Errorf("expected IPv4 restriction to reject ::1, got %v", problems)
+	}
+	if problems := ValidateVar("10.0.0.1", Rule{Type: TypeIP, IPVersion: 6}); len(problems) != 1 {
+		t.Errorf("expected IPv6 restriction to reject 10.0.0.1, got %v", problems)
+	}
+	if problems := ValidateVar("10.0.0.0/8", Rule{Type: TypeCIDR, IPVersion: 4}); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestValidateVar_TypeCIDR(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.0/8":  "",
+		"fd00::/8":    "",
+		"10.0.0.0":    "cidr: missing prefix length",
+		"10.0.0.x/8":  "cidr: invalid address",
+		"10.0.0.0/33": "cidr: invalid prefix length",
+	}
+	for v, want := range tests {
+		problems := ValidateVar(v, Rule{Type: TypeCIDR})
+		if want == "" {
+			if len(problems) != 0 {
+				t.Errorf("%q: expected no problems, got %v", v, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 || problems[0] != want {
+			t.Errorf("%q: expected %q, got %v", v, want, problems)
+		}
+	}
+}
+
+func TestValidateVar_TypeHostname(t *testing.T) {
+	tests := map[string]string{
+		"db.internal":      "",
+		"example.com.":     "",
+		"3com.net":         "",
+		"-bad.example.com": "hostname: label starts or ends with hyphen",
+		"a..b":             "hostname: empty label",
+		"under_score.com":  "hostname: invalid character",
+		"":                 "hostname: empty",
+	}
+	for v, want := range tests {
+		problems := ValidateVar(v, Rule{Type: TypeHostname})
+		if want == "" {
+			if len(problems) != 0 {
+				t.Errorf("%q: expected no problems, got %v", v, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 || problems[0] != want {
+			t.Errorf("%q: expected %q, got %v", v, want, problems)
+		}
+	}
+}
+
+func TestValidateVar_TypePort(t *testing.T) {
+	if problems := ValidateVar("5432", Rule{Type: TypePort}); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+	if problems := ValidateVar("0", Rule{Type: TypePort}); len(problems) != 1 || problems[0] != "port: out of range 1-65535" {
+		t.Errorf("expected range problem, got %v", problems)
+	}
+	if problems := ValidateVar("http", Rule{Type: TypePort}); len(problems) != 1 || problems[0] != "port: not a number" {
+		t.Errorf("expected number problem, got %v", problems)
+	}
+	if problems := ValidateVar("80", Rule{Type: TypePort, Min: "1024"}); len(problems) != 1 {
+		t.Errorf("expected port range problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_TypeHostPort(t *testing.T) {
+	tests := map[string]string{
+		"db.internal:5432":  "",
+		"10.0.0.1:6379":     "",
+		"[::1]:8080":        "",
+		"db.internal":       "hostport: missing port",
+		":5432":             "hostport: missing host",
+		"db_host:5432":      "hostport: invalid host",
+		"db.internal:99999": "hostport: invalid port",
+		"::1:8080":          "hostport: malformed host:port",
+	}
+	for v, want := range tests {
+		problems := ValidateVar(v, Rule{Type: TypeHostPort})
+		if want == "" {
+			if len(problems) != 0 {
+				t.Errorf("%q: expected no problems, got %v", v, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 || problems[0] != want {
+			t.Errorf("%q: expected %q, got %v", v, want, problems)
+		}
+	}
+}
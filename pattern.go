@@ -0,0 +1,171 @@
+package envdoc
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+)
+
+// isPattern reports whether the rule matches a family of keys via
+// KeyPattern or KeyRegex instead of a single Key.
+func (r Rule) isPattern() bool {
+	return r.KeyPattern != "" || r.KeyRegex != ""
+}
+
+// name identifies the rule in messages: its key, or its pattern.
+func (r Rule) name() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	return r.pattern()
+}
+
+// pattern returns the rule's key pattern as written in the rules file.
+func (r Rule) pattern() string {
+	if r.KeyPattern != "" {
+		return r.KeyPattern
+	}
+	return r.KeyRegex
+}
+
+// keyMatcher matches environment variable names against a pattern rule.
+type keyMatcher struct {
+	rule Rule
+	re   *regexp.Regexp
+}
+
+func newKeyMatcher(r Rule) (keyMatcher, error) {
+	m := keyMatcher{rule: r}
+	if r.KeyRegex != "" {
+		re, err := regexp.Compile(r.KeyRegex)
+		if err != nil {
+			return m, err
+		}
+		m.re = re
+		return m, nil
+	}
+	if _, err := path.Match(r.KeyPattern, ""); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func (m keyMatcher) match(key string) bool {
+	if m.re != nil {
+		return m.re.MatchString(key)
+	}
+	ok, _ := path.Match(m.rule.KeyPattern, key)
+	return ok
+}
+
+// Matches reports whether the rule applies to key: its Key equals key or
+// its key_pattern or key_regex matches it.
+func (r Rule) Matches(key string) bool {
+	if !r.isPattern() {
+		return r.Key == key
+	}
+	m, err := newKeyMatcher(r)
+	return err == nil && m.match(key)
+}
+
+// PatternResult holds the match count for a single pattern rule.
+type PatternResult struct {
+	Pattern  string    `json:"pattern"`
+	Matches  int       `json:"matches"`
+	Valid    bool      `json:"valid"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// addProblem records p on the pattern result with the rule's severity.
+func (pr *PatternResult) addProblem(rule Rule, p Problem) {
+	p.Severity = rule.severityOf(p.Field)
+	p.DocsURL = rule.DocsURL
+	pr.Problems = append(pr.Problems, p)
+}
+
+// expandPatterns assigns each environment variable without an explicit rule
+// to the first pattern rule that matches it. It returns the concrete rule for
+// each matched key (with Key filled in), the matched keys in sorted order,
+// and one PatternResult per pattern rule.
+func expandPatterns(envKeys []string, rules []Rule, explicit map[string]Rule) (map[string]Rule, []string, []PatternResult) {
+	var matchers []keyMatcher
+	for _, r := range rules {
+		if !r.isPattern() {
+			continue
+		}
+		if m, err := newKeyMatcher(r); err == nil {
+			matchers = append(matchers, m)
+		}
+	}
+	if len(matchers) == 0 {
+		return nil, nil, nil
+	}
+
+	sorted := append([]string(nil), envKeys...)
+	sort.Strings(sorted)
+
+	matched := make(map[string]Rule)
+	var keys []string
+	counts := make([]int, len(matchers))
+	for _, k := range sorted {
+		if _, ok := explicit[k]; ok {
+			continue
+		}
+		for mi, m := range matchers {
+			if m.match(k) {
+				r := m.rule
+				r.Key = k
+				matched[k] = r
+				keys = append(keys, k)
+				counts[mi]++
+				break
+			}
+		}
+	}
+
+	results := make([]PatternResult, len(matchers))
+	for mi, m := range matchers {
+		pr := PatternResult{Pattern: m.rule.pattern(), Matches: counts[mi]}
+		if m.rule.MinMatches != nil && pr.Matches < *m.rule.MinMatches {
+			pr.addProblem(m.rule, newProblem(CodeTooFewMatches, "min_matches", *m.rule.MinMatches, "%d matches < min_matches %d", pr.Matches, *m.rule.MinMatches))
+		}
+		if m.rule.MaxMatches != nil && pr.Matches > *m.rule.MaxMatches {
+			pr.addProblem(m.rule, newProblem(CodeTooManyMatches, "max_matches", *m.rule.MaxMatches, "%d matches > max_matches %d", pr.Matches, *m.rule.MaxMatches))
+		}
+		pr.Valid = !hasSeverity(pr.Problems, SeverityError)
+		results[mi] = pr
+	}
+	return matched, keys, results
+}
+
+// validatePattern checks the key fields of a rule: exactly one of key,
+// key_pattern and key_regex, a compilable pattern, sensible match limits,
+// and no per-key requirement clauses on pattern rules.
+func validatePattern(r Rule) error {
+	set := 0
+	for _, s := range []string{r.Key, r.KeyPattern, r.KeyRegex} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of key, key_pattern and key_regex may be set")
+	}
+	if !r.isPattern() {
+		if r.MinMatches != nil || r.MaxMatches != nil {
+			return fmt.Errorf("min_matches and max_matches require key_pattern or key_regex")
+		}
+		return nil
+	}
+	if _, err := newKeyMatcher(r); err != nil {
+		return fmt.Errorf("invalid key pattern: %w", err)
+	}
+	if r.Required || len(r.RequiredIf) > 0 || len(r.RequiredUnless) > 0 || len(r.Compare) > 0 {
+		return fmt.Errorf("required, required_if, required_unless and compare are not supported on pattern rules; use min_matches")
+	}
+	if r.MinMatches != nil && r.MaxMatches != nil && *r.MinMatches > *r.MaxMatches {
+		return fmt.Errorf("min_matches (%d) > max_matches (%d)", *r.MinMatches, *r.MaxMatches)
+	}
+	return nil
+}
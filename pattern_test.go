@@ -0,0 +1,135 @@
+package envdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect_KeyPattern(t *testing.T) {
+	env := MapEnvReader{
+		"FEATURE_SEARCH":  "true",
+		"FEATURE_BILLING": "maybe",
+		"FEATURE_LEGACY":  "custom",
+		"OTHER":           "x",
+	}
+	rules := []Rule{
+		{Key: "FEATURE_LEGACY", Allowed: []string{"custom"}},
+		{KeyPattern: "FEATURE_*", Type: TypeBool, MinMatches: intPtr(1)},
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{Mode: ModeAllowlist}, nil)
+
+	if len(report.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d: %+v", len(report.Results), report.Results)
+	}
+	// Explicit key first, then pattern matches in sorted order.
+	if r := report.Results[0]; r.Key != "FEATURE_LEGACY" || r.Pattern != "" || !r.Valid {
+		t.Errorf("expected explicit rule to win for FEATURE_LEGACY: %+v", r)
+	}
+	if r := report.Results[1]; r.Key != "FEATURE_BILLING" || r.Pattern != "FEATURE_*" || r.Valid {
+		t.Errorf("expected FEATURE_BILLING invalid via pattern: %+v", r)
+	}
+	if r := report.Results[2]; r.Key != "FEATURE_SEARCH" || r.Pattern != "FEATURE_*" || !r.Valid {
+		t.Errorf("expected FEATURE_SEARCH valid via pattern: %+v", r)
+	}
+
+	if len(report.Patterns) != 1 {
+		t.Fatalf("expected 1 pattern result, got %d", len(report.Patterns))
+	}
+	if p := report.Patterns[0]; p.Matches != 2 || !p.Valid {
+		t.Errorf("unexpected pattern result: %+v", p)
+	}
+}
+
+func TestInspect_KeyRegexMatchLimits(t *testing.T) {
+	env := MapEnvReader{"TENANT_1_DSN": "a", "TENANT_2_DSN": "b", "TENANT_X_DSN": "c"}
+	rules := []Rule{
+		{KeyRegex: `^TENANT_\d+_DSN$`, MaxMatches: intPtr(1)},
+		{KeyPattern: "SHARD_*", MinMatches: intPtr(1)},
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{Mode: ModeAllowlist}, nil)
+
+	if len(report.Results) != 2 {
+		t.Errorf("expected 2 matched results, got %d", len(report.Results))
+	}
+	if p := report.Patterns[0]; p.Valid || p.Problems[0].Message != "2 matches > max_matches 1" {
+		t.Errorf("unexpected regex pattern result: %+v", p)
+	}
+	if p := report.Patterns[1]; p.Valid || p.Problems[0].Message != "0 matches < min_matches 1" {
+		t.Errorf("unexpected glob pattern result: %+v", p)
+	}
+
+	err := CheckFailFast(report, true)
+	if err == nil || !strings.Contains(err.Error(), "2 pattern(s) invalid") {
+		t.Errorf("expected pattern fail-fast error, got %v", err)
+	}
+}
+
+func TestInspect_KeyPatternDumpAll(t *testing.T) {
+	env := MapEnvReader{"FEATURE_X": "nope", "HOME": "/root"}
+	rules := []Rule{{KeyPattern: "FEATURE_*", Type: TypeBool}}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{Mode: ModeDumpAll, DumpAll: true}, nil)
+
+	if len(report.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(report.Results))
+	}
+	for _, r := range report.Results {
+		if r.Key == "FEATURE_X" && (r.Valid || r.Pattern != "FEATURE_*") {
+			t.Errorf("expected FEATURE_X validated by pattern: %+v", r)
+		}
+		if r.Key == "HOME" && r.Pattern != "" {
+			t.Errorf("expected HOME unmatched: %+v", r)
+		}
+	}
+}
+
+func TestValidateRules_Patterns(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"valid", []Rule{{KeyPattern: "FEATURE_*", Type: TypeBool}, {KeyRegex: `^TENANT_\d+_DSN$`}}, ""},
+		{"key and pattern", []Rule{{Key: "A", KeyPattern: "A*"}}, "only one of key"},
+		{"bad glob", []Rule{{KeyPattern: "FEATURE_["}}, "invalid key pattern"},
+		{"bad regex", []Rule{{KeyRegex: "("}}, "invalid key pattern"},
+		{"duplicate", []Rule{{KeyPattern: "A*"}, {KeyPattern: "A*"}}, `duplicate key pattern "A*"`},
+		{"required", []Rule{{KeyPattern: "A*", Required: true}}, "not supported on pattern rules"},
+		{"matches without pattern", []Rule{{Key: "A", MinMatches: intPtr(1)}}, "require key_pattern"},
+		{"min > max", []Rule{{KeyPattern: "A*", MinMatches: intPtr(2), MaxMatches: intPtr(1)}}, "min_matches (2) > max_matches (1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules(tt.rules)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		key  string
+		want bool
+	}{
+		{Rule{Key: "DB_HOST"}, "DB_HOST", true},
+		{Rule{Key: "DB_HOST"}, "DB_PORT", false},
+		{Rule{KeyPattern: "FEATURE_*"}, "FEATURE_X", true},
+		{Rule{KeyPattern: "FEATURE_*"}, "FEATURES", false},
+		{Rule{KeyRegex: `^TENANT_\d+_DSN$`}, "TENANT_7_DSN", true},
+		{Rule{KeyRegex: `(`}, "(", false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Matches(tt.key); got != tt.want {
+			t.Errorf("%s.Matches(%q) = %t, want %t", tt.rule.name(), tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package envdoc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks how serious a problem is.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityRank = map[Severity]int{
+	SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3,
+}
+
+// AtLeast reports whether s is as severe as t or more.
+func (s Severity) AtLeast(t Severity) bool {
+	return severityRank[s] >= severityRank[t]
+}
+
+// ParseSeverity parses "error", "warning" or "info".
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityRank[sev]; !ok {
+		return "", fmt.Errorf("unknown severity %q (want error, warning or info)", s)
+	}
+	return sev, nil
+}
+
+// ProblemCode is a stable, machine-readable problem identifier. Codes are
+// never renamed; alert on them rather than on messages.
+type ProblemCode string
+
+const (
+	CodeMissing       ProblemCode = "ENV_MISSING"
+	CodeForbidden     ProblemCode = "ENV_FORBIDDEN"
+	CodeTypeInvalid   ProblemCode = "ENV_TYPE_INVALID"
+	CodeIPVersion     ProblemCode = "ENV_IP_VERSION_MISMATCH"
+	CodeBelowMin      ProblemCode = "ENV_BELOW_MIN"
+	CodeAboveMax      ProblemCode = "ENV_ABOVE_MAX"
+	CodeTooShort      ProblemCode = "ENV_TOO_SHORT"
+	CodeTooLong       ProblemCode = "ENV_TOO_LONG"
+	CodeRegexMismatch ProblemCode = "ENV_REGEX_MISMATCH"
+	CodeJSONSchema    ProblemCode = "ENV_SCHEMA_MISMATCH"
+	CodeNotAllowed    ProblemCode = "ENV_NOT_ALLOWED"
+	CodeTooFewItems   ProblemCode = "ENV_TOO_FEW_ITEMS"
+	CodeTooManyItems  ProblemCode = "ENV_TOO_MANY_ITEMS"
+	CodeEmptyItem     ProblemCode = "ENV_EMPTY_ITEM"
+	CodeDuplicateItem ProblemCode = "ENV_DUPLICATE_ITEM"
+	CodeMapMalformed  ProblemCode = "ENV_MAP_MALFORMED"
+	CodeKeyNotAllowed ProblemCode = "ENV_KEY_NOT_ALLOWED"
+	CodeMissingKey    ProblemCode = "ENV_MISSING_KEY"
+	CodeCompareFailed ProblemCode = "ENV_COMPARE_FAILED"
+	CodeDeprecated    ProblemCode = "ENV_DEPRECATED"
+	CodeConflict      ProblemCode = "ENV_CONFLICT"
+	CodeExpired       ProblemCode = "ENV_DEPRECATION_EXPIRED"
+	CodeWhitespace    ProblemCode = "ENV_WHITESPACE"
+	CodeBindFailed    ProblemCode = "ENV_BIND_FAILED"
+	CodeExprFailed    ProblemCode = "ENV_EXPR_FAILED"
+	CodeExprInvalid   ProblemCode = "ENV_EXPR_INVALID"
+
+	CodeAssertionFailed ProblemCode = "ENV_ASSERTION_FAILED"
+
+	CodeGroupNoneSet    ProblemCode = "ENV_GROUP_NONE_SET"
+	CodeGroupMultiple   ProblemCode = "ENV_GROUP_MULTIPLE_SET"
+	CodeGroupIncomplete ProblemCode = "ENV_GROUP_INCOMPLETE"
+	CodeGroupExclusive  ProblemCode = "ENV_GROUP_EXCLUSIVE"
+
+	CodeTooFewMatches  ProblemCode = "ENV_PATTERN_TOO_FEW"
+	CodeTooManyMatches ProblemCode = "ENV_PATTERN_TOO_MANY"
+)
+
+// Problem is a single finding on a variable, group or pattern. Field names
+// the rule field whose check produced it (e.g. "required", "min_len") and
+// Expected holds that field's constraint (e.g. 16 for min_len). Neither
+// Expected nor Message ever echoes the value. DocsURL is the rule's
+// docs_url, if any.
+type Problem struct {
+	Code     ProblemCode `json:"code"`
+	Field    string      `json:"field"`
+	Severity Severity    `json:"severity"`
+	Expected any         `json:"expected,omitempty"`
+	Message  string      `json:"message"`
+	DocsURL  string      `json:"docs_url,omitempty"`
+}
+
+// String renders the problem as "CODE: message", followed by
+// " (see URL)" when it has a DocsURL, so logs and fail-fast errors link to
+// the docs too.
+func (p Problem) String() string {
+	s := string(p.Code) + ": " + p.Message
+	if p.DocsURL != "" {
+		s += " (see " + p.DocsURL + ")"
+	}
+	return s
+}
+
+// newProblem builds a Problem; its severity is set by the rule.
+func newProblem(code ProblemCode, field string, expected any, format string, args ...any) Problem {
+	return Problem{Code: code, Field: field, Expected: expected, Message: fmt.Sprintf(format, args...)}
+}
+
+// severityFields are the check names that may appear in a rule's severities
+// map. Checks not listed as warnings default to error.
+var severityFields = map[string]Severity{
+	"required": SeverityError, "required_if": SeverityError, "required_unless": SeverityError,
+	"forbidden_if": SeverityError,
+	"type":         SeverityError, "item_type": SeverityError, "ip_version": SeverityError,
+	"min": SeverityError, "max": SeverityError,
+	"min_len": SeverityError, "max_len": SeverityError,
+	"regex": SeverityError, "allowed": SeverityError, "json_schema": SeverityError,
+	"min_items": SeverityError, "max_items": SeverityError, "unique": SeverityError,
+	"required_keys": SeverityError, "allowed_keys": SeverityError,
+	"compare":    SeverityError,
+	"deprecated": SeverityWarning, "replaced_by": SeverityError, "fail_after": SeverityError,
+	"trimmed":     SeverityWarning,
+	"min_matches": SeverityError, "max_matches": SeverityError,
+	"expr": SeverityError,
+}
+
+// severityOf returns the severity of problems from the rule's field check:
+// the severities entry for field, else the rule's severity, else the
+// check's default.
+func (r Rule) severityOf(field string) Severity {
+	if s, ok := r.Severities[field]; ok {
+		return s
+	}
+	if r.Severity != "" {
+		return r.Severity
+	}
+	if s, ok := severityFields[field]; ok {
+		return s
+	}
+	return SeverityError
+}
+
+// addProblem records p on vr with the rule's severity for p.Field and its
+// docs URL. Only error-level problems make the variable invalid.
+func (vr *VarResult) addProblem(rule Rule, p Problem) {
+	p.Severity = rule.severityOf(p.Field)
+	p.DocsURL = rule.DocsURL
+	if p.Severity == SeverityError {
+		vr.Valid = false
+	}
+	vr.Problems = append(vr.Problems, p)
+}
+
+// hasSeverity reports whether any problem is at or above threshold.
+func hasSeverity(problems []Problem, threshold Severity) bool {
+	for _, p := range problems {
+		if p.Severity.AtLeast(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// problemMessages returns the messages of problems.
+func problemMessages(problems []Problem) []string {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Message
+	}
+	return msgs
+}
+
+// problemStrings returns problems rendered with their codes.
+func problemStrings(problems []Problem) []string {
+	strs := make([]string, len(problems))
+	for i, p := range problems {
+		strs[i] = p.String()
+	}
+	return strs
+}
+
+// validateSeverities checks the rule's severity and severities fields.
+func validateSeverities(r Rule) error {
+	if _, ok := severityRank[r.Severity]; r.Severity != "" && !ok {
+		return fmt.Errorf("unknown severity %q (want error, warning or info)", r.Severity)
+	}
+	for field, s := range r.Severities {
+		if _, ok := severityFields[field]; !ok {
+			return fmt.Errorf("severities: unknown check %q", field)
+		}
+		if _, ok := severityRank[s]; !ok {
+			return fmt.Errorf("severities: %s: unknown severity %q (want error, warning or info)", field, s)
+		}
+	}
+	return nil
+}
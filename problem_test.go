@@ -0,0 +1,241 @@
+package envdoc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRule_SeverityOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		field string
+		want  Severity
+	}{
+		{"default error", Rule{}, "min_len", SeverityError},
+		{"default warning", Rule{}, "trimmed", SeverityWarning},
+		{"rule severity", Rule{Severity: SeverityInfo}, "regex", SeverityInfo},
+		{"per check", Rule{Severity: SeverityInfo, Severities: map[string]Severity{"required": SeverityError}}, "required", SeverityError},
+		{"escalate warning", Rule{Severities: map[string]Severity{"trimmed": SeverityError}}, "trimmed", SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.severityOf(tt.field); got != tt.want {
+				t.Errorf("severityOf(%q) = %s, want %s", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspect_Severities(t *testing.T) {
+	env := MapEnvReader{
+		"DB_PASSWORD": "short ",
+		"LOG_FORMAT":  "xml",
+	}
+	rules := []Rule{
+		{Key: "DB_PASSWORD", Required: true, MinLen: intPtr(16)},
+		{Key: "LOG_FORMAT", Allowed: []string{"json", "text"}, Severity: SeverityWarning},
+		{Key: "OPTIONAL_URL", Type: TypeURL, RequiredIf: []Condition{{Key: "LOG_FORMAT"}}, Severities: map[string]Severity{"required_if": SeverityInfo}},
+	}
+	report := inspect(env, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	pw := report.Results[0]
+	if pw.Valid {
+		t.Error("expected DB_PASSWORD invalid")
+	}
+	want := []Problem{
+		{Code: CodeTooShort, Field: "min_len", Severity: SeverityError, Expected: 16, Message: "length 6 < min_len 16"},
+		{Code: CodeWhitespace, Field: "trimmed", Severity: SeverityWarning, Message: "leading or trailing whitespace"},
+	}
+	if len(pw.Problems) != len(want) {
+		t.Fatalf("expected %v, got %v", want, pw.Problems)
+	}
+	for i := range want {
+		if pw.Problems[i] != want[i] {
+			t.Errorf("problem[%d] = %+v, want %+v", i, pw.Problems[i], want[i])
+		}
+	}
+
+	if lf := report.Results[1]; !lf.Valid || lf.Problems[0].Severity != SeverityWarning {
+		t.Errorf("expected LOG_FORMAT valid with a warning, got %+v", lf)
+	}
+	if u := report.Results[2]; !u.Valid || u.Problems[0] != (Problem{Code: CodeMissing, Field: "required_if", Severity: SeverityInfo, Expected: "LOG_FORMAT is set", Message: "required but not set"}) {
+		t.Errorf("expected OPTIONAL_URL info, got %+v", u)
+	}
+
+	if report.Summary.Errors != 1 || report.Summary.Warnings != 2 {
+		t.Errorf("expected 1 error and 2 warnings, got %+v", report.Summary)
+	}
+}
+
+func TestCheckFailOn(t *testing.T) {
+	report := &Report{
+		Results: []VarResult{
+			{Key: "A", Present: true, Valid: true, Required: true,
+				Problems: []Problem{{Field: "trimmed", Severity: SeverityWarning, Message: "leading or trailing whitespace"}}},
+			{Key: "B", Present: true, Valid: true,
+				Problems: []Problem{{Field: "trimmed", Severity: SeverityWarning, Message: "leading or trailing whitespace"}}},
+			{Key: "C", Present: true, Valid: true, Required: true,
+				Problems: []Problem{{Field: "allowed", Severity: SeverityInfo, Message: "value not in allowed set [a]"}}},
+		},
+	}
+
+	if err := CheckFailOn(report, SeverityError); err != nil {
+		t.Errorf("expected no error at error threshold, got %v", err)
+	}
+	err := CheckFailOn(report, SeverityWarning)
+	if err == nil || !strings.Contains(err.Error(), "2 variable(s) invalid") {
+		t.Fatalf("expected 2 failures at warning threshold, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "A: ") || !strings.Contains(err.Error(), "B: ") {
+		t.Errorf("expected A and optional B in error, got %v", err)
+	}
+	err = CheckFailOn(report, SeverityInfo)
+	if err == nil || !strings.Contains(err.Error(), "3 variable(s) invalid") {
+		t.Errorf("expected 3 failures at info threshold, got %v", err)
+	}
+}
+
+func TestRun_FailOnWarningOptional(t *testing.T) {
+	env := MapEnvReader{"REDIS_ADDR": "cache:6379", "LOG_LEVEL": "info "}
+	rules := []Rule{
+		{Key: "REDIS_ADDR", Type: TypeHostPort, Deprecated: true},
+		{Key: "LOG_LEVEL", Allowed: []string{"debug", "info"}},
+	}
+	report, err := Run(
+		WithEnvReader(env),
+		WithClock(fixedClock{t: time.Now()}),
+		WithRules(rules),
+		WithConfig(LoadConfig(MapEnvReader{"ENVDOC_FAIL_ON": "warning"})),
+		WithOutput(&strings.Builder{}),
+	)
+	if report.Summary.Warnings != 2 {
+		t.Fatalf("expected 2 warnings, got %+v", report.Summary)
+	}
+	if err == nil || !strings.Contains(err.Error(), "REDIS_ADDR") || !strings.Contains(err.Error(), "LOG_LEVEL") {
+		t.Errorf("expected fail-fast on warnings of optional variables, got %v", err)
+	}
+
+	_, err = Run(
+		WithEnvReader(env),
+		WithClock(fixedClock{t: time.Now()}),
+		WithRules(rules),
+		WithConfig(LoadConfig(MapEnvReader{"ENVDOC_FAIL_FAST": "true"})),
+		WithOutput(&strings.Builder{}),
+	)
+	if err == nil || strings.Contains(err.Error(), "REDIS_ADDR") || !strings.Contains(err.Error(), "LOG_LEVEL") {
+		t.Errorf("expected only the invalid LOG_LEVEL to fail at the default threshold, got %v", err)
+	}
+}
+
+func TestCheckFailOn_OptionalInvalid(t *testing.T) {
+	rules := []Rule{{Key: "WORKERS", Type: TypeInt}, {Key: "MODE", Allowed: []string{"a"}, Severity: SeverityWarning}}
+	report := inspect(MapEnvReader{"WORKERS": "abc", "MODE": "b"}, fixedClock{t: time.Now()}, RuleSet{Rules: rules}, Config{}, nil)
+
+	// The threshold decides which severities count, not which variables.
+	for _, tt := range []struct {
+		threshold Severity
+		want      string
+	}{
+		{SeverityError, "1 variable(s) invalid"},
+		{SeverityWarning, "2 variable(s) invalid"},
+	} {
+		err := CheckFailOn(report, tt.threshold)
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "WORKERS: ") {
+			t.Errorf("threshold %s: expected %q naming WORKERS, got %v", tt.threshold, tt.want, err)
+		}
+	}
+}
+
+func TestRun_FailOnWarning(t *testing.T) {
+	env := MapEnvReader{"DB_HOST": "db "}
+	_, err := Run(
+		WithEnvReader(env),
+		WithClock(fixedClock{t: time.Now()}),
+		WithRules([]Rule{{Key: "DB_HOST", Required: true}}),
+		WithConfig(LoadConfig(MapEnvReader{"ENVDOC_FAIL_ON": "warning"})),
+		WithOutput(&strings.Builder{}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+		t.Errorf("expected fail-fast on whitespace warning, got %v", err)
+	}
+}
+
+func TestValidateRules_Severities(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"unknown severity", Rule{Key: "A", Severity: "fatal"}, `unknown severity "fatal"`},
+		{"unknown check", Rule{Key: "A", Severities: map[string]Severity{"color": SeverityInfo}}, `unknown check "color"`},
+		{"unknown check severity", Rule{Key: "A", Severities: map[string]Severity{"regex": "loud"}}, `regex: unknown severity "loud"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules([]Rule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckVar_Codes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		rule     Rule
+		code     ProblemCode
+		field    string
+		expected any
+	}{
+		{"type", "abc", Rule{Type: TypeInt}, CodeTypeInvalid, "type", TypeInt},
+		{"regex", "abc", Rule{Regex: "^[0-9]+$"}, CodeRegexMismatch, "regex", "^[0-9]+$"},
+		{"min_len", "abc", Rule{MinLen: intPtr(5)}, CodeTooShort, "min_len", 5},
+		{"max", "20", Rule{Type: TypeInt, Max: "10"}, CodeAboveMax, "max", "10"},
+		{"ip_version", "::1", Rule{Type: TypeIP, IPVersion: 4}, CodeIPVersion, "ip_version", 4},
+		{"list item type", "1,x", Rule{Type: TypeList, ItemType: TypeInt}, CodeTypeInvalid, "item_type", TypeInt},
+		{"unique", "a,a", Rule{Type: TypeList, Unique: true}, CodeDuplicateItem, "unique", true},
+		{"map required key", "a=1", Rule{Type: TypeMap, RequiredKeys: []string{"b"}}, CodeMissingKey, "required_keys", "b"},
+		{"map malformed", "a", Rule{Type: TypeMap}, CodeMapMalformed, "type", TypeMap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := checkVar(tt.value, tt.rule, nil)
+			if len(problems) != 1 {
+				t.Fatalf("expected 1 problem, got %v", problems)
+			}
+			p := problems[0]
+			if p.Code != tt.code || p.Field != tt.field || p.Expected != tt.expected {
+				t.Errorf("got code=%s field=%s expected=%v, want %s %s %v", p.Code, p.Field, p.Expected, tt.code, tt.field, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReport_ProblemJSON(t *testing.T) {
+	rules := []Rule{{Key: "DB_PASSWORD", Required: true, Secret: boolPtr(true)}}
+	groups := []Group{{Name: "db", AllOrNone: KeySet{"DB_HOST", "DB_PORT"}}}
+	report := inspect(MapEnvReader{"DB_HOST": "db"}, fixedClock{t: time.Now()}, RuleSet{Rules: rules, Groups: groups}, Config{}, nil)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`{"code":"ENV_MISSING","field":"required","severity":"error","expected":true,"message":"required but not set"}`,
+		`"code":"ENV_GROUP_INCOMPLETE","field":"all_or_none"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+
+	err = CheckFailFast(report, true)
+	if err == nil || !strings.Contains(err.Error(), "problems=[ENV_MISSING: required but not set]") {
+		t.Errorf("expected coded problem in fail-fast error, got %v", err)
+	}
+}
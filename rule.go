@@ -2,9 +2,10 @@ package envdoc
 
 import (
 	"fmt"
+	"io/fs"
+	"path/filepath"
 	"regexp"
-
-	"gopkg.in/yaml.v3"
+	"slices"
 )
 
 // VarType represents the expected type of an environment variable value.
@@ -13,84 +14,525 @@ type VarType string
 const (
 	TypeString   VarType = "string"
 	TypeInt      VarType = "int"
+	TypeFloat    VarType = "float"
 	TypeBool     VarType = "bool"
 	TypeDuration VarType = "duration"
 	TypeURL      VarType = "url"
 	TypeJSON     VarType = "json"
+	TypeIP       VarType = "ip"
+	TypeCIDR     VarType = "cidr"
+	TypeHostname VarType = "hostname"
+	TypePort     VarType = "port"
+	TypeHostPort VarType = "hostport"
+	TypeList     VarType = "list"
+	TypeMap      VarType = "map"
 )
 
 var validTypes = map[VarType]bool{
-	TypeString: true, TypeInt: true, TypeBool: true,
+	TypeString: true, TypeInt: true, TypeFloat: true, TypeBool: true,
 	TypeDuration: true, TypeURL: true, TypeJSON: true,
+	TypeIP: true, TypeCIDR: true, TypeHostname: true, TypePort: true, TypeHostPort: true,
+	TypeList: true, TypeMap: true,
 }
 
+// Default separators for list and map values.
+const (
+	defaultListSeparator = ","
+	defaultKVSeparator   = "="
+)
+
 // Rule defines validation for a single environment variable.
+//
+// Min and Max bound the parsed value for int, float, duration and port types
+// and are written in the same syntax as the value (e.g. "1" or "30s"). Bounds
+// are inclusive unless ExclusiveMin/ExclusiveMax is set.
+//
+// IPVersion restricts ip and cidr values to IPv4 (4) or IPv6 (6).
+//
+// For type list, the value is split on Separator and each item is checked
+// against ItemType, Min/Max, IPVersion, Regex and Allowed; MinLen/MaxLen
+// still apply to the whole value. Type map splits the value into key/value
+// pairs on Separator and KVSeparator and applies the same item checks to
+// each value.
+//
+// RequiredIf makes the variable required when any of its conditions hold,
+// RequiredUnless makes it required unless any of them hold, and ForbiddenIf
+// rejects the variable when it is set while any of them hold.
+//
+// Compare relates the variable's parsed value to other variables' values.
+//
+// JSONSchema checks a json value's structure (see jsonschema.go).
+//
+// Deprecated marks a variable that should no longer be set. ReplacedBy names
+// its successor and RemovalDate when it goes away; both are informational.
+// Once the clock passes FailAfter, a set deprecated variable is invalid and
+// fails fail-fast. Dates are YYYY-MM-DD (midnight UTC) or RFC 3339.
+//
+// Severity sets the severity of the rule's problems, and Severities overrides
+// it per check, keyed by rule field (e.g. "min_len", "trimmed"). By default
+// deprecation and whitespace problems are warnings and the rest are errors.
+//
+// Expr is a boolean expression (see expr.go) that must hold while the
+// variable is set; ExprMessage is reported when it does not.
+//
+// Description, Example, Owner, DocsURL and Group document the variable for
+// WriteMarkdown and WriteEnvExample (see docs.go); Group names its section,
+// which otherwise comes from the key's prefix. Problems on the variable
+// carry DocsURL.
+//
+// Instead of Key, a rule may set KeyPattern (a glob such as "FEATURE_*") or
+// KeyRegex to apply to every variable whose name matches. Explicit keys take
+// precedence over patterns, and MinMatches/MaxMatches bound how many
+// variables a pattern may match.
 type Rule struct {
-	Key         string   `yaml:"key"`
-	Required    bool     `yaml:"required"`
-	Type        VarType  `yaml:"type"`
-	MinLen      *int     `yaml:"min_len,omitempty"`
-	MaxLen      *int     `yaml:"max_len,omitempty"`
-	Regex       string   `yaml:"regex,omitempty"`
-	Allowed     []string `yaml:"allowed,omitempty"`
-	Secret      *bool    `yaml:"secret,omitempty"`
-	Fingerprint *bool    `yaml:"fingerprint,omitempty"`
+	Key          string   `yaml:"key"`
+	KeyPattern   string   `yaml:"key_pattern,omitempty"`
+	KeyRegex     string   `yaml:"key_regex,omitempty"`
+	MinMatches   *int     `yaml:"min_matches,omitempty"`
+	MaxMatches   *int     `yaml:"max_matches,omitempty"`
+	Required     bool     `yaml:"required"`
+	Default      *string  `yaml:"default,omitempty"`
+	Type         VarType  `yaml:"type"`
+	MinLen       *int     `yaml:"min_len,omitempty"`
+	MaxLen       *int     `yaml:"max_len,omitempty"`
+	Min          string   `yaml:"min,omitempty"`
+	Max          string   `yaml:"max,omitempty"`
+	ExclusiveMin bool     `yaml:"exclusive_min,omitempty"`
+	ExclusiveMax bool     `yaml:"exclusive_max,omitempty"`
+	IPVersion    int      `yaml:"ip_version,omitempty"`
+	Regex        string   `yaml:"regex,omitempty"`
+	Allowed      []string `yaml:"allowed,omitempty"`
+	Secret       *bool    `yaml:"secret,omitempty"`
+	Fingerprint  *bool    `yaml:"fingerprint,omitempty"`
+	Separator    string   `yaml:"separator,omitempty"`
+	ItemType     VarType  `yaml:"item_type,omitempty"`
+	MinItems     *int     `yaml:"min_items,omitempty"`
+	MaxItems     *int     `yaml:"max_items,omitempty"`
+	Unique       bool     `yaml:"unique,omitempty"`
+	KVSeparator  string   `yaml:"kv_separator,omitempty"`
+	RequiredKeys []string `yaml:"required_keys,omitempty"`
+	AllowedKeys  []string `yaml:"allowed_keys,omitempty"`
+
+	RequiredIf     []Condition `yaml:"required_if,omitempty"`
+	RequiredUnless []Condition `yaml:"required_unless,omitempty"`
+	ForbiddenIf    []Condition `yaml:"forbidden_if,omitempty"`
+
+	Compare []Comparison `yaml:"compare,omitempty"`
+
+	JSONSchema *JSONSchema `yaml:"json_schema,omitempty"`
+
+	Deprecated  bool   `yaml:"deprecated,omitempty"`
+	ReplacedBy  string `yaml:"replaced_by,omitempty"`
+	RemovalDate string `yaml:"removal_date,omitempty"`
+	FailAfter   string `yaml:"fail_after,omitempty"`
+
+	Severity   Severity            `yaml:"severity,omitempty"`
+	Severities map[string]Severity `yaml:"severities,omitempty"`
+
+	Expr        string `yaml:"expr,omitempty"`
+	ExprMessage string `yaml:"expr_message,omitempty"`
+
+	// FingerprintNote says why a secret is fingerprinted anyway; it
+	// silences the lint warning about it.
+	FingerprintNote string `yaml:"fingerprint_note,omitempty"`
+
+	Description string `yaml:"description,omitempty"`
+	Example     string `yaml:"example,omitempty"`
+	Owner       string `yaml:"owner,omitempty"`
+	DocsURL     string `yaml:"docs_url,omitempty"`
+	Group       string `yaml:"group,omitempty"`
+
+	// source is the file the rule was loaded from, for error messages.
+	source string
+	// expr is Expr compiled by validateRules, so inspection does not parse
+	// it again; nil for rules that were never validated.
+	expr *compiledExpr
+}
+
+// listSeparator returns the separator used to split list items and map pairs.
+func (r Rule) listSeparator() string {
+	if r.Separator != "" {
+		return r.Separator
+	}
+	return defaultListSeparator
+}
+
+// kvSeparator returns the separator between a map key and its value.
+func (r Rule) kvSeparator() string {
+	if r.KVSeparator != "" {
+		return r.KVSeparator
+	}
+	return defaultKVSeparator
+}
+
+// isCollection reports whether the rule's value is split into elements.
+func (r Rule) isCollection() bool {
+	return r.Type == TypeList || r.Type == TypeMap
+}
+
+// elementRule returns the rule applied to each list item or map value.
+func (r Rule) elementRule() Rule {
+	return Rule{
+		Key:          r.Key,
+		Type:         r.ItemType,
+		Min:          r.Min,
+		Max:          r.Max,
+		ExclusiveMin: r.ExclusiveMin,
+		ExclusiveMax: r.ExclusiveMax,
+		IPVersion:    r.IPVersion,
+		Regex:        r.Regex,
+		Allowed:      r.Allowed,
+	}
 }
 
 // RuleSet is the top-level YAML structure.
+//
+// Include lists other rules files, relative to the including file, whose
+// rules, groups and assertions are merged in before this file's own. Override patches
+// fields of included rules; redefining an included key is an error.
+// Loaded RuleSets have their includes and overrides already resolved.
+//
+// Profiles maps a profile name (e.g. "prod") to patches that ApplyProfile
+// applies on top of the rules. ActiveProfile records the applied profile.
+//
+// Schema is the "$schema" key editors read to find the rules file schema
+// (see WriteRuleSetSchema); envdoc itself ignores it.
 type RuleSet struct {
-	Rules []Rule `yaml:"rules"`
+	Schema     string                 `yaml:"$schema,omitempty"`
+	Include    []string               `yaml:"include,omitempty"`
+	Rules      []Rule                 `yaml:"rules"`
+	Override   []RulePatch            `yaml:"override,omitempty"`
+	Groups     []Group                `yaml:"groups,omitempty"`
+	Assertions []Assertion            `yaml:"assertions,omitempty"`
+	Profiles   map[string][]RulePatch `yaml:"profiles,omitempty"`
+
+	ActiveProfile string `yaml:"-"`
 }
 
-// LoadRules parses YAML bytes into a slice of Rules.
-func LoadRules(data []byte) ([]Rule, error) {
+// ApplyProfile patches the rules with the named profile and records it as
+// the active profile. An empty name is a no-op; an unknown name is an error.
+func (rs *RuleSet) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	if rs.ActiveProfile != "" {
+		return fmt.Errorf("envdoc: profile %q already applied", rs.ActiveProfile)
+	}
+	patches, ok := rs.Profiles[name]
+	if !ok {
+		return fmt.Errorf("envdoc: unknown profile %q", name)
+	}
+	rules, err := profileRules(rs.Rules, name, patches)
+	if err != nil {
+		return err
+	}
+	// Recompile the assertions, since the profile may change the types of
+	// the variables they read.
+	assertions := slices.Clone(rs.Assertions)
+	if err := validateAssertions(assertions, rules); err != nil {
+		return fmt.Errorf("envdoc: profile %q: %w", name, err)
+	}
+	rs.Rules = rules
+	rs.Assertions = assertions
+	rs.ActiveProfile = name
+	return nil
+}
+
+// profileRules returns a patched and validated copy of rules.
+func profileRules(rules []Rule, name string, patches []RulePatch) ([]Rule, error) {
+	out := append([]Rule(nil), rules...)
+	if err := applyPatches(out, patches, fmt.Sprintf("envdoc: profile %q", name)); err != nil {
+		return nil, err
+	}
+	if err := validateRules(out); err != nil {
+		return nil, fmt.Errorf("envdoc: profile %q: %w", name, err)
+	}
+	return out, nil
+}
+
+// LoadRuleSet parses and validates YAML (or JSON) bytes into a RuleSet.
+// Includes are not available without a file; use LoadRuleSetFile or
+// LoadRuleSetFS.
+func LoadRuleSet(data []byte) (*RuleSet, error) {
 	var rs RuleSet
-	if err := yaml.Unmarshal(data, &rs); err != nil {
+	if err := decodeYAMLStrict(data, &rs); err != nil {
 		return nil, fmt.Errorf("envdoc: parsing rules: %w", err)
 	}
-	if err := validateRules(rs.Rules); err != nil {
+	return finishRuleSet(new(loader).resolve(&rs, ""))
+}
+
+// LoadRuleSetTOML is like LoadRuleSet for TOML bytes.
+func LoadRuleSetTOML(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := decodeTOML(data, &rs); err != nil {
+		return nil, fmt.Errorf("envdoc: parsing rules: %w", err)
+	}
+	return finishRuleSet(new(loader).resolve(&rs, ""))
+}
+
+// LoadRuleSetFile reads and parses a rules file into a RuleSet, resolving
+// includes relative to the file. Include cycles are an error. Files ending
+// in .toml are parsed as TOML and others as YAML, which includes JSON; an
+// included file may use a different format than the file including it.
+func LoadRuleSetFile(path string) (*RuleSet, error) {
+	return finishRuleSet(newOSLoader().loadFile(filepath.Clean(path)))
+}
+
+// LoadRuleSetFS is like LoadRuleSetFile but reads from fsys, so rules and
+// shared fragments can be shipped with go:embed.
+func LoadRuleSetFS(fsys fs.FS, path string) (*RuleSet, error) {
+	return finishRuleSet(newFSLoader(fsys).loadFile(path))
+}
+
+// finishRuleSet validates a resolved RuleSet.
+func finishRuleSet(rs *RuleSet, err error) (*RuleSet, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRuleSet(rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// LoadRules parses YAML bytes into a slice of Rules.
+func LoadRules(data []byte) ([]Rule, error) {
+	rs, err := LoadRuleSet(data)
+	if err != nil {
 		return nil, err
 	}
 	return rs.Rules, nil
 }
 
-// LoadRulesFile reads and parses a YAML rules file.
+// LoadRulesFile reads and parses a rules file.
 func LoadRulesFile(path string) ([]Rule, error) {
-	data, err := readFile(path)
+	rs, err := LoadRuleSetFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("envdoc: reading rules file: %w", err)
+		return nil, err
+	}
+	return rs.Rules, nil
+}
+
+// LoadRulesFS is like LoadRulesFile but reads from fsys.
+func LoadRulesFS(fsys fs.FS, path string) ([]Rule, error) {
+	rs, err := LoadRuleSetFS(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	return rs.Rules, nil
+}
+
+// validateRuleSet validates the rules and groups of rs, and the rules each
+// profile would produce.
+func validateRuleSet(rs *RuleSet) error {
+	if err := validateRules(rs.Rules); err != nil {
+		return err
+	}
+	if err := validateGroups(rs.Groups); err != nil {
+		return err
+	}
+	if err := validateAssertions(rs.Assertions, rs.Rules); err != nil {
+		return err
+	}
+	for name, patches := range rs.Profiles {
+		if name == "" {
+			return fmt.Errorf("envdoc: profile name is required")
+		}
+		rules, err := profileRules(rs.Rules, name, patches)
+		if err != nil {
+			return err
+		}
+		if err := validateAssertions(slices.Clone(rs.Assertions), rules); err != nil {
+			return fmt.Errorf("envdoc: profile %q: %w", name, err)
+		}
 	}
-	return LoadRules(data)
+	return nil
 }
 
-// validateRules checks rules for duplicate keys, unknown types, invalid regex, and min>max.
+// validateRules checks rules for duplicate keys or patterns, unknown types, invalid regex and JSON schemas, min>max,
+// value ranges that do not fit the rule's type, malformed conditions, deprecation dates and
+// severities, comparisons against unknown or incompatible keys, and expressions that do not compile.
 func validateRules(rules []Rule) error {
-	seen := make(map[string]bool)
+	seen := make(map[string]int)
 	for idx, r := range rules {
-		if r.Key == "" {
+		if r.Key == "" && !r.isPattern() {
 			return fmt.Errorf("envdoc: rule[%d]: key is required", idx)
 		}
-		if seen[r.Key] {
-			return fmt.Errorf("envdoc: rule[%d]: duplicate key %q", idx, r.Key)
+		if err := validatePattern(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+		if first, dup := seen[r.name()]; dup {
+			what := "key"
+			if r.isPattern() {
+				what = "key pattern"
+			}
+			if src := rules[first].source; src != "" || r.source != "" {
+				return fmt.Errorf("envdoc: rule[%d]: duplicate %s %q (defined in %s and %s)", idx, what, r.name(), sourceName(src), sourceName(r.source))
+			}
+			return fmt.Errorf("envdoc: rule[%d]: duplicate %s %q", idx, what, r.name())
 		}
-		seen[r.Key] = true
+		seen[r.name()] = idx
 
-		if r.Type != "" && !validTypes[r.Type] {
-			return fmt.Errorf("envdoc: rule[%d] (%s): unknown type %q", idx, r.Key, r.Type)
+		if r.Type != "" && !knownType(r.Type) {
+			return fmt.Errorf("envdoc: rule[%d] (%s): unknown type %q", idx, r.name(), r.Type)
 		}
 
 		if r.Regex != "" {
 			if _, err := regexp.Compile(r.Regex); err != nil {
-				return fmt.Errorf("envdoc: rule[%d] (%s): invalid regex: %w", idx, r.Key, err)
+				return fmt.Errorf("envdoc: rule[%d] (%s): invalid regex: %w", idx, r.name(), err)
+			}
+		}
+
+		if r.JSONSchema != nil {
+			if r.Type != TypeJSON {
+				return fmt.Errorf("envdoc: rule[%d] (%s): json_schema requires type json", idx, r.name())
+			}
+			if err := validateJSONSchema(r.JSONSchema, ""); err != nil {
+				return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
 			}
 		}
 
+		if r.FingerprintNote != "" && (r.Fingerprint == nil || !*r.Fingerprint) {
+			return fmt.Errorf("envdoc: rule[%d] (%s): fingerprint_note requires fingerprint: true", idx, r.name())
+		}
+
 		if r.MinLen != nil && r.MaxLen != nil && *r.MinLen > *r.MaxLen {
-			return fmt.Errorf("envdoc: rule[%d] (%s): min_len (%d) > max_len (%d)", idx, r.Key, *r.MinLen, *r.MaxLen)
+			return fmt.Errorf("envdoc: rule[%d] (%s): min_len (%d) > max_len (%d)", idx, r.name(), *r.MinLen, *r.MaxLen)
+		}
+
+		if err := validateCollection(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		if err := validateDefault(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		if err := validateDocs(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		if err := validateDeprecation(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		if err := validateSeverities(r); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		// For lists and maps, value constraints describe the elements.
+		vr := r
+		if r.isCollection() {
+			vr = r.elementRule()
+		}
+
+		if err := validateRange(vr); err != nil {
+			return fmt.Errorf("envdoc: rule[%d] (%s): %w", idx, r.name(), err)
+		}
+
+		if vr.IPVersion != 0 {
+			if vr.IPVersion != 4 && vr.IPVersion != 6 {
+				return fmt.Errorf("envdoc: rule[%d] (%s): ip_version must be 4 or 6, got %d", idx, r.name(), vr.IPVersion)
+			}
+			if vr.Type != TypeIP && vr.Type != TypeCIDR {
+				return fmt.Errorf("envdoc: rule[%d] (%s): ip_version requires type ip or cidr", idx, r.name())
+			}
+		}
+	}
+	if err := validateConditions(rules); err != nil {
+		return err
+	}
+	if err := validateComparisons(rules); err != nil {
+		return err
+	}
+	return validateExprs(rules)
+}
+
+// validateCollection checks that list and map fields are only used with
+// those types and that element types and count limits are sensible.
+func validateCollection(r Rule) error {
+	if !r.isCollection() {
+		if r.Separator != "" || r.ItemType != "" || r.MinItems != nil || r.MaxItems != nil {
+			return fmt.Errorf("separator, item_type, min_items and max_items require type list or map")
+		}
+	}
+	if r.Type != TypeList && r.Unique {
+		return fmt.Errorf("unique requires type list")
+	}
+	if r.Type != TypeMap && (r.KVSeparator != "" || len(r.RequiredKeys) > 0 || len(r.AllowedKeys) > 0) {
+		return fmt.Errorf("kv_separator, required_keys and allowed_keys require type map")
+	}
+	if !r.isCollection() {
+		return nil
+	}
+	if r.ItemType != "" && (!knownType(r.ItemType) || r.ItemType == TypeList || r.ItemType == TypeMap) {
+		return fmt.Errorf("unknown item_type %q", r.ItemType)
+	}
+	if r.MinItems != nil && r.MaxItems != nil && *r.MinItems > *r.MaxItems {
+		return fmt.Errorf("min_items (%d) > max_items (%d)", *r.MinItems, *r.MaxItems)
+	}
+	if r.Type == TypeMap {
+		if r.listSeparator() == r.kvSeparator() {
+			return fmt.Errorf("separator and kv_separator must differ")
+		}
+		if len(r.AllowedKeys) > 0 {
+			for _, k := range r.RequiredKeys {
+				if !slices.Contains(r.AllowedKeys, k) {
+					return fmt.Errorf("required key %q not in allowed_keys", k)
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// validateRange checks that min/max are only used with ordered types, parse
+// as that type, and do not describe an empty range.
+func validateRange(r Rule) error {
+	if r.Min == "" && r.Max == "" {
+		if r.ExclusiveMin || r.ExclusiveMax {
+			return fmt.Errorf("exclusive_min/exclusive_max require min/max")
+		}
+		return nil
+	}
+	if !orderedTypes[r.Type] {
+		return fmt.Errorf("min/max require type int, float, duration or port, got %q", r.Type)
+	}
+	if r.ExclusiveMin && r.Min == "" {
+		return fmt.Errorf("exclusive_min requires min")
+	}
+	if r.ExclusiveMax && r.Max == "" {
+		return fmt.Errorf("exclusive_max requires max")
+	}
+
+	var min, max any
+	var err error
+	if r.Min != "" {
+		if min, err = parseValue(r.Min, r.Type); err != nil {
+			return fmt.Errorf("min %q: %w", r.Min, err)
+		}
+	}
+	if r.Max != "" {
+		if max, err = parseValue(r.Max, r.Type); err != nil {
+			return fmt.Errorf("max %q: %w", r.Max, err)
+		}
+	}
+	if min != nil && max != nil {
+		c := compareParsed(min, max)
+		if c > 0 || (c == 0 && (r.ExclusiveMin || r.ExclusiveMax)) {
+			return fmt.Errorf("min (%s) > max (%s)", r.Min, r.Max)
+		}
+	}
+	return nil
+}
+
+// sourceName describes where a rule was defined for error messages.
+func sourceName(source string) string {
+	if source == "" {
+		return "inline rules"
+	}
+	return source
+}
+
 // readFile is a helper to read a file. Separated for testability.
 var readFile = readFileOS
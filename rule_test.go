@@ -101,6 +101,64 @@ func TestLoadRules_MinGreaterThanMax(t *testing.T) {
 	}
 }
 
+func TestLoadRules_InvalidRange(t *testing.T) {
+	data, err := os.ReadFile("testdata/invalid_range.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadRules(data)
+	if err == nil {
+		t.Fatal("expected error for min > max")
+	}
+	if !strings.Contains(err.Error(), "min (65535) > max (1)") {
+		t.Errorf("expected min/max error, got: %v", err)
+	}
+}
+
+func TestValidateRules_TypeConstraints(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"valid int", Rule{Key: "A", Type: TypeInt, Min: "1", Max: "10"}, ""},
+		{"valid duration", Rule{Key: "A", Type: TypeDuration, Min: "1s", Max: "1h"}, ""},
+		{"wrong type", Rule{Key: "A", Type: TypeString, Min: "1"}, "require type int"},
+		{"unparsable bound", Rule{Key: "A", Type: TypeDuration, Max: "soon"}, "not a valid duration"},
+		{"empty exclusive range", Rule{Key: "A", Type: TypeInt, Min: "5", Max: "5", ExclusiveMax: true}, "min (5) > max (5)"},
+		{"exclusive without bound", Rule{Key: "A", Type: TypeInt, ExclusiveMin: true}, "require min/max"},
+		{"port range", Rule{Key: "A", Type: TypePort, Min: "1024"}, ""},
+		{"ip version", Rule{Key: "A", Type: TypeIP, IPVersion: 4}, ""},
+		{"bad ip version", Rule{Key: "A", Type: TypeIP, IPVersion: 5}, "ip_version must be 4 or 6"},
+		{"ip version wrong type", Rule{Key: "A", Type: TypeHostname, IPVersion: 4}, "requires type ip or cidr"},
+		{"list item range", Rule{Key: "A", Type: TypeList, ItemType: TypeInt, Min: "1"}, ""},
+		{"list untyped item range", Rule{Key: "A", Type: TypeList, Min: "1"}, "require type int"},
+		{"list nested", Rule{Key: "A", Type: TypeList, ItemType: TypeList}, "unknown item_type"},
+		{"list item counts", Rule{Key: "A", Type: TypeList, MinItems: intPtr(3), MaxItems: intPtr(1)}, "min_items (3) > max_items (1)"},
+		{"list fields without list", Rule{Key: "A", Type: TypeString, Separator: ";"}, "require type list"},
+		{"unique on map", Rule{Key: "A", Type: TypeMap, Unique: true}, "unique requires type list"},
+		{"map fields without map", Rule{Key: "A", Type: TypeList, RequiredKeys: []string{"x"}}, "require type map"},
+		{"map same separators", Rule{Key: "A", Type: TypeMap, Separator: "=", KVSeparator: "="}, "must differ"},
+		{"fingerprint note", Rule{Key: "A", Fingerprint: boolPtr(true), FingerprintNote: "random token"}, ""},
+		{"fingerprint note without fingerprint", Rule{Key: "A", FingerprintNote: "random token"}, "fingerprint_note requires fingerprint: true"},
+		{"map required not allowed", Rule{Key: "A", Type: TypeMap, RequiredKeys: []string{"x"}, AllowedKeys: []string{"y"}}, "not in allowed_keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRules([]Rule{tt.rule})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
 func TestLoadRulesFile(t *testing.T) {
 	rules, err := LoadRulesFile("testdata/basic_rules.yaml")
 	if err != nil {
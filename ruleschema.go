@@ -0,0 +1,124 @@
+package envdoc
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ruleSchemaRequired lists the fields each rules file struct needs, as far
+// as a JSON Schema can say; the loader checks the rest.
+var ruleSchemaRequired = map[reflect.Type][]string{
+	reflect.TypeFor[Condition]():  {"key"},
+	reflect.TypeFor[Comparison](): {"op", "key"},
+	reflect.TypeFor[Group]():      {"name"},
+	reflect.TypeFor[Assertion]():  {"name", "expr"},
+}
+
+// WriteRuleSetSchema writes a JSON Schema (draft 2020-12) for rules files
+// to w, for editors to complete and check rules.yaml with. It is generated
+// from RuleSet and its field types, so it lists exactly the fields the
+// loader accepts; value checks beyond types and enums are left to loading.
+func WriteRuleSetSchema(w io.Writer) error {
+	defs := make(map[string]any)
+	root := structSchema(reflect.TypeFor[RuleSet](), defs)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "envdoc rules file"
+	root["$defs"] = defs
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// scalarSchema matches any YAML scalar, since the decoder reads numbers and
+// booleans into string fields as written (e.g. "min: 1").
+var scalarSchema = map[string]any{"type": []string{"string", "number", "boolean"}}
+
+// typeSchema returns the schema of a field of type t, adding the structs it
+// refers to to defs.
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	switch t {
+	case reflect.TypeFor[VarType]():
+		// Built-in types are offered for completion, but types registered
+		// with RegisterType are valid too.
+		names := make([]string, 0, len(validTypes))
+		for _, typ := range slices.Sorted(maps.Keys(validTypes)) {
+			names = append(names, string(typ))
+		}
+		return map[string]any{"anyOf": []any{map[string]any{"enum": names}, map[string]any{"type": "string"}}}
+	case reflect.TypeFor[Severity]():
+		return map[string]any{"enum": []Severity{SeverityError, SeverityWarning, SeverityInfo}}
+	case reflect.TypeFor[CompareOp]():
+		return map[string]any{"enum": []CompareOp{CompareLT, CompareLE, CompareEQ, CompareNE, CompareSameHost}}
+	case reflect.TypeFor[KeySet]():
+		return map[string]any{"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	case reflect.TypeFor[JSONSchema]():
+		return map[string]any{
+			"description": "Inline JSON Schema subset, or the path of a schema file relative to the rules file",
+			"type":        []string{"object", "string"},
+		}
+	case reflect.TypeFor[RulePatch]():
+		// A patch is written like a rule.
+		return typeSchema(reflect.TypeFor[Rule](), defs)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.String:
+		defs["scalar"] = scalarSchema
+		return map[string]any{"$ref": "#/$defs/scalar"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // placeholder for recursive types
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// structSchema returns the schema of struct t: its YAML fields, and no
+// others.
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	props := make(map[string]any)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if name := yamlName(f); name != "" {
+			props[name] = typeSchema(f.Type, defs)
+		}
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if req, ok := ruleSchemaRequired[t]; ok {
+		s["required"] = req
+	}
+	if t == reflect.TypeFor[Rule]() {
+		s["anyOf"] = []any{
+			map[string]any{"required": []string{"key"}},
+			map[string]any{"required": []string{"key_pattern"}},
+			map[string]any{"required": []string{"key_regex"}},
+		}
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package envdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWriteRuleSetSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRuleSetSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties           map[string]map[string]any `json:"properties"`
+			AdditionalProperties bool                      `json:"additionalProperties"`
+			Required             []string                  `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"$schema", "include", "rules", "override", "groups", "assertions", "profiles"} {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("expected top-level property %q", key)
+		}
+	}
+	rule := schema.Defs["Rule"]
+	if rule.AdditionalProperties {
+		t.Error("expected Rule to reject additional properties")
+	}
+	if len(rule.Properties) != len(yamlFields(reflect.TypeFor[Rule]())) {
+		t.Errorf("expected a property per rule field, got %d", len(rule.Properties))
+	}
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"min_len", `{"type":"integer"}`},
+		{"min", `{"$ref":"#/$defs/scalar"}`},
+		{"severity", `{"enum":["error","warning","info"]}`},
+		{"severities", `{"additionalProperties":{"enum":["error","warning","info"]},"type":"object"}`},
+		{"required_if", `{"items":{"$ref":"#/$defs/Condition"},"type":"array"}`},
+		{"json_schema", `{"description":"Inline JSON Schema subset, or the path of a schema file relative to the rules file","type":["object","string"]}`},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(rule.Properties[tt.field])
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.field, got, tt.want)
+		}
+	}
+	if got := schema.Defs["Assertion"].Required; !reflect.DeepEqual(got, []string{"name", "expr"}) {
+		t.Errorf("expected assertion name and expr required, got %v", got)
+	}
+}
+
+func TestRuleSetSchema_Shipped(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRuleSetSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	shipped, err := os.ReadFile("rules.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shipped, buf.Bytes()) {
+		t.Error("rules.schema.json is out of date; run: go run ./cmd/envdoc schema -o rules.schema.json")
+	}
+}
@@ -0,0 +1,386 @@
+package envdoc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFromStruct derives rules from the tags of a config struct, so the
+// struct that receives the environment also documents it. v is a struct or
+// a pointer to one; only its type is used.
+//
+// Each field tagged env:"KEY" becomes a rule for KEY. The env tag may also
+// carry the options required, notEmpty (required with min_len 1) and
+// secret. The type is inferred from the field: integers are int, with min
+// and max set to what sized and unsigned integers can hold, floats
+// float, bool bool, time.Duration duration, url.URL url, net.IP and
+// netip.Addr ip, net.IPNet and netip.Prefix cidr, json.RawMessage json,
+// slices list and maps map, with the element type as item_type. Pointers
+// are followed. envSeparator and envKeyValSeparator set the list and map
+// separators, and envDefault sets the default.
+//
+// The envdoc tag sets any other rule field by its rules-file name, as
+// comma-separated name=value pairs; bare names are true and list fields
+// such as allowed take |-separated values:
+//
+//	Port int    `env:"PORT,required" envdoc:"type=port,min=1024"`
+//	Mode string `env:"MODE" envdoc:"allowed=dev|prod,severity=warning"`
+//
+// Struct fields without an env tag are walked for more rules. Their keys
+// get the envPrefix tag as a prefix, or else the field name in upper snake
+// case and an underscore (DB -> "DB_", HTTPServer -> "HTTP_SERVER_");
+// embedded structs get no prefix. Fields tagged env:"-" are skipped, and so
+// are fields that point back to a struct being walked (Parent *Config), which
+// would otherwise nest forever.
+//
+// The rules are validated as LoadRules would validate them.
+func RulesFromStruct(v any) ([]Rule, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("envdoc: RulesFromStruct needs a struct, got %T", v)
+	}
+	var rules []Rule
+	for _, sf := range structFields(t, "", t.Name(), nil, nil) {
+		r, err := fieldRule(sf.key, sf.opts, sf.field)
+		if err != nil {
+			return nil, fmt.Errorf("envdoc: field %s: %w", sf.path, err)
+		}
+		rules = append(rules, r)
+	}
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// structField is a field of a config struct tagged with an env key. index
+// is its index sequence from the outermost struct, through nested structs
+// and pointers to them, and path names it for error messages.
+type structField struct {
+	key   string
+	opts  string
+	field reflect.StructField
+	index []int
+	path  string
+}
+
+// structFields returns the env-tagged fields of struct type t and the
+// structs nested in it. prefix is prepended to keys. outer holds the structs
+// t is nested in; fields of those types are not walked again.
+func structFields(t reflect.Type, prefix, path string, index []int, outer []reflect.Type) []structField {
+	outer = append(slices.Clip(outer), t)
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// Embedded structs of unexported types still promote exported fields.
+		if !f.IsExported() && !(f.Anonymous && ft.Kind() == reflect.Struct) {
+			continue
+		}
+		name, tagged := f.Tag.Lookup("env")
+		key, opts, _ := strings.Cut(name, ",")
+		if key == "-" {
+			continue
+		}
+		fieldIndex := append(slices.Clip(index), i)
+		fieldPath := path + "." + f.Name
+
+		if !tagged || key == "" {
+			if ft.Kind() != reflect.Struct || fieldType(ft) != "" || slices.Contains(outer, ft) {
+				continue
+			}
+			sub := prefix
+			if p, ok := f.Tag.Lookup("envPrefix"); ok {
+				sub += p
+			} else if !f.Anonymous {
+				sub += upperSnake(f.Name) + "_"
+			}
+			fields = append(fields, structFields(ft, sub, fieldPath, fieldIndex, outer)...)
+			continue
+		}
+		fields = append(fields, structField{key: prefix + key, opts: opts, field: f, index: fieldIndex, path: fieldPath})
+	}
+	return fields
+}
+
+// fieldRule builds the rule for a field tagged env:"key,opts".
+func fieldRule(key, opts string, f reflect.StructField) (Rule, error) {
+	r := Rule{Key: key}
+	typ, item, err := inferType(f.Type)
+	if err != nil {
+		return r, err
+	}
+	r.Type, r.ItemType = typ, item
+	r.Min, r.Max = intBounds(f.Type)
+	r.Separator = f.Tag.Get("envSeparator")
+	r.KVSeparator = f.Tag.Get("envKeyValSeparator")
+	if def, ok := f.Tag.Lookup("envDefault"); ok {
+		r.Default = &def
+	}
+
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "required":
+			r.Required = true
+		case "notEmpty":
+			r.Required = true
+			r.MinLen = intPointer(1)
+		case "secret":
+			secret := true
+			r.Secret = &secret
+		}
+	}
+
+	if tag, ok := f.Tag.Lookup("envdoc"); ok {
+		if err := applyTagOptions(&r, tag); err != nil {
+			return r, fmt.Errorf("envdoc tag: %w", err)
+		}
+	}
+	return r, nil
+}
+
+var (
+	durationType   = reflect.TypeFor[time.Duration]()
+	urlType        = reflect.TypeFor[url.URL]()
+	ipType         = reflect.TypeFor[net.IP]()
+	ipNetType      = reflect.TypeFor[net.IPNet]()
+	addrType       = reflect.TypeFor[netip.Addr]()
+	prefixType     = reflect.TypeFor[netip.Prefix]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	textType       = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// fieldType returns the VarType of well-known Go types and "" otherwise.
+func fieldType(t reflect.Type) VarType {
+	switch t {
+	case durationType:
+		return TypeDuration
+	case urlType:
+		return TypeURL
+	case ipType, addrType:
+		return TypeIP
+	case ipNetType, prefixType:
+		return TypeCIDR
+	case rawMessageType:
+		return TypeJSON
+	}
+	if reflect.PointerTo(t).Implements(textType) {
+		return TypeString
+	}
+	return ""
+}
+
+// inferType returns the rule type and item type for a field type.
+func inferType(t reflect.Type) (VarType, VarType, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if typ := fieldType(t); typ != "" {
+		return typ, "", nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypeString, "", nil
+		}
+		item, _, err := inferType(t.Elem())
+		if err != nil || item == TypeList || item == TypeMap {
+			return "", "", fmt.Errorf("unsupported list element type %s", t.Elem())
+		}
+		return TypeList, item, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return "", "", fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		item, _, err := inferType(t.Elem())
+		if err != nil || item == TypeList || item == TypeMap {
+			return "", "", fmt.Errorf("unsupported map value type %s", t.Elem())
+		}
+		return TypeMap, item, nil
+	}
+	typ, err := kindType(t.Kind())
+	if err != nil {
+		return "", "", fmt.Errorf("unsupported type %s", t)
+	}
+	return typ, "", nil
+}
+
+// intBounds returns the range of values an integer field, or the elements
+// of an integer list or map field, can hold, so that validation rejects what
+// binding could not store. Sizes whose range int parsing already enforces get
+// no bound.
+func intBounds(t reflect.Type) (min, max string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if fieldType(t) != "" {
+		return "", ""
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "", ""
+		}
+		return intBounds(t.Elem())
+	case reflect.Map:
+		return intBounds(t.Elem())
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return strconv.FormatInt(-1<<(t.Bits()-1), 10), strconv.FormatInt(1<<(t.Bits()-1)-1, 10)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "0", strconv.FormatUint(1<<t.Bits()-1, 10)
+	case reflect.Uint, reflect.Uint64:
+		return "0", ""
+	}
+	return "", ""
+}
+
+// kindType maps a basic kind to its VarType.
+func kindType(k reflect.Kind) (VarType, error) {
+	switch k {
+	case reflect.String:
+		return TypeString, nil
+	case reflect.Bool:
+		return TypeBool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt, nil
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat, nil
+	}
+	return "", fmt.Errorf("unsupported kind %s", k)
+}
+
+// tagListFields are the rule fields whose envdoc tag values are lists.
+var tagListFields = map[string]bool{"allowed": true, "required_keys": true, "allowed_keys": true}
+
+// tagDeniedFields are rule fields the envdoc tag may not set: the key comes
+// from the env tag and the rest are not plain values.
+var tagDeniedFields = map[string]bool{
+	"key": true, "key_pattern": true, "key_regex": true,
+	"required_if": true, "required_unless": true, "forbidden_if": true,
+	"compare": true, "severities": true, "json_schema": true,
+}
+
+// applyTagOptions sets rule fields from an envdoc tag. Options are split on
+// commas that start a new name, so values such as regexes may contain
+// commas. The options are decoded like a rules file entry, so values are
+// checked against the field types and unknown names are errors.
+func applyTagOptions(r *Rule, tag string) error {
+	var opts []string
+	for _, part := range strings.Split(tag, ",") {
+		name, _, _ := strings.Cut(part, "=")
+		if len(opts) > 0 && !isTagOptionName(name) {
+			opts[len(opts)-1] += "," + part
+			continue
+		}
+		opts = append(opts, part)
+	}
+
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	for _, opt := range opts {
+		if opt == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(opt, "=")
+		if tagDeniedFields[name] {
+			return fmt.Errorf("option %q cannot be set in a tag", name)
+		}
+		var v *yaml.Node
+		switch {
+		case !hasValue:
+			v = &yaml.Node{Kind: yaml.ScalarNode, Value: "true"}
+		case tagListFields[name]:
+			v = &yaml.Node{Kind: yaml.SequenceNode}
+			for _, item := range strings.Split(value, "|") {
+				v.Content = append(v.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+			}
+		default:
+			v = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			if name == "regex" || name == "expr" || name == "expr_message" {
+				v.Tag = "!!str"
+			}
+		}
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, v)
+	}
+
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(r); err != nil {
+		var te *yaml.TypeError
+		if errors.As(err, &te) {
+			return fmt.Errorf("%s", strings.Join(stripLines(te.Errors), "; "))
+		}
+		return err
+	}
+	return nil
+}
+
+// isTagOptionName reports whether s looks like an option name.
+func isTagOptionName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// stripLines drops the "line N: " prefixes of decoder errors, which refer
+// to the generated document rather than the tag.
+func stripLines(msgs []string) []string {
+	out := make([]string, len(msgs))
+	for i, msg := range msgs {
+		if m := lineErrorRe.FindStringSubmatch(msg); m != nil {
+			msg = m[2]
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// upperSnake converts a Go field name to UPPER_SNAKE_CASE, keeping
+// initialisms together: HTTPServer -> HTTP_SERVER.
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func intPointer(n int) *int { return &n }
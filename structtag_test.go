@@ -0,0 +1,211 @@
+package envdoc
+
+import (
+	"encoding/json"
+	"io"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDBConfig struct {
+	Host     string `env:"HOST,required" envdoc:"type=hostname"`
+	Port     int    `env:"PORT" envdoc:"type=port,min=1024"`
+	Password string `env:"PASSWORD,notEmpty,secret" envdoc:"min_len=16"`
+}
+
+type testCommon struct {
+	LogLevel string `env:"LOG_LEVEL" envdoc:"allowed=debug|info|warn,severity=warning"`
+}
+
+type testConfig struct {
+	testCommon
+	ServiceID string            `env:"SERVICE_ID" envdoc:"regex=^svc-[a-z]{1,8}$"`
+	Timeout   time.Duration     `env:"TIMEOUT" envdoc:"min=1s,max=1m"`
+	Ratio     *float64          `env:"RATIO"`
+	Debug     bool              `env:"DEBUG"`
+	PublicURL *url.URL          `env:"PUBLIC_URL"`
+	Peer      netip.Addr        `env:"PEER" envdoc:"ip_version=4"`
+	Flags     json.RawMessage   `env:"FLAGS"`
+	Brokers   []string          `env:"BROKERS" envSeparator:";" envdoc:"min_items=1,unique"`
+	Ports     []uint16          `env:"PORTS"`
+	Labels    map[string]string `env:"LABELS" envKeyValSeparator:":" envdoc:"required_keys=team"`
+	Primary   testDBConfig      `envPrefix:"DB_"`
+	HTTPCache struct {
+		TTL time.Duration `env:"TTL"`
+	}
+	Ignored  string `env:"-"`
+	Untagged string
+	internal string `env:"INTERNAL"`
+}
+
+func TestRulesFromStruct(t *testing.T) {
+	rules, err := RulesFromStruct(&testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byKey := keyedRules(rules)
+	var keys []string
+	for _, r := range rules {
+		keys = append(keys, r.Key)
+	}
+	want := "LOG_LEVEL,SERVICE_ID,TIMEOUT,RATIO,DEBUG,PUBLIC_URL,PEER,FLAGS,BROKERS,PORTS,LABELS,DB_HOST,DB_PORT,DB_PASSWORD,HTTP_CACHE_TTL"
+	if got := strings.Join(keys, ","); got != want {
+		t.Fatalf("keys:\n got %s\nwant %s", got, want)
+	}
+
+	checks := []struct {
+		key string
+		ok  bool
+	}{
+		{"LOG_LEVEL", byKey["LOG_LEVEL"].Type == TypeString && len(byKey["LOG_LEVEL"].Allowed) == 3 && byKey["LOG_LEVEL"].Severity == SeverityWarning},
+		{"SERVICE_ID", byKey["SERVICE_ID"].Regex == "^svc-[a-z]{1,8}$"},
+		{"TIMEOUT", byKey["TIMEOUT"].Type == TypeDuration && byKey["TIMEOUT"].Min == "1s" && byKey["TIMEOUT"].Max == "1m"},
+		{"RATIO", byKey["RATIO"].Type == TypeFloat},
+		{"DEBUG", byKey["DEBUG"].Type == TypeBool},
+		{"PUBLIC_URL", byKey["PUBLIC_URL"].Type == TypeURL},
+		{"PEER", byKey["PEER"].Type == TypeIP && byKey["PEER"].IPVersion == 4},
+		{"FLAGS", byKey["FLAGS"].Type == TypeJSON},
+		{"BROKERS", byKey["BROKERS"].Type == TypeList && byKey["BROKERS"].ItemType == TypeString && byKey["BROKERS"].Separator == ";" && *byKey["BROKERS"].MinItems == 1 && byKey["BROKERS"].Unique},
+		{"PORTS", byKey["PORTS"].Type == TypeList && byKey["PORTS"].ItemType == TypeInt},
+		{"LABELS", byKey["LABELS"].Type == TypeMap && byKey["LABELS"].KVSeparator == ":" && byKey["LABELS"].RequiredKeys[0] == "team"},
+		{"DB_HOST", byKey["DB_HOST"].Type == TypeHostname && byKey["DB_HOST"].Required},
+		{"DB_PORT", byKey["DB_PORT"].Type == TypePort && byKey["DB_PORT"].Min == "1024" && !byKey["DB_PORT"].Required},
+		{"DB_PASSWORD", byKey["DB_PASSWORD"].Required && *byKey["DB_PASSWORD"].Secret && *byKey["DB_PASSWORD"].MinLen == 16},
+		{"HTTP_CACHE_TTL", byKey["HTTP_CACHE_TTL"].Type == TypeDuration},
+	}
+	for _, c := range checks {
+		if !c.ok {
+			t.Errorf("%s: unexpected rule %+v", c.key, byKey[c.key])
+		}
+	}
+
+	report := New(
+		WithEnvReader(MapEnvReader{"DB_HOST": "db.internal", "DB_PORT": "80", "DB_PASSWORD": "short"}),
+		WithRules(rules),
+	).Inspect()
+	if report.Summary.Errors != 2 {
+		t.Errorf("expected errors for DB_PORT and DB_PASSWORD, got %+v", report.Summary)
+	}
+}
+
+func TestRulesFromStruct_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"not a struct", 42, "RulesFromStruct needs a struct, got int"},
+		{"unsupported type", struct {
+			C chan int `env:"C"`
+		}{}, "unsupported type chan int"},
+		{"nested list", struct {
+			L [][]string `env:"L"`
+		}{}, "unsupported list element type []string"},
+		{"denied option", struct {
+			A string `env:"A" envdoc:"severities=x"`
+		}{}, `option "severities" cannot be set in a tag`},
+		{"unknown option", struct {
+			A string `env:"A" envdoc:"min_length=3"`
+		}{}, "envdoc tag: field min_length not found"},
+		{"bad value", struct {
+			A string `env:"A" envdoc:"min_len=three"`
+		}{}, "cannot unmarshal !!str `three` into int"},
+		{"invalid rule", struct {
+			A int `env:"A" envdoc:"min=5,max=1"`
+		}{}, "rule[0] (A): min (5) > max (1)"},
+		{"duplicate key", struct {
+			A string `env:"A"`
+			B string `env:"A"`
+		}{}, `duplicate key "A"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RulesFromStruct(tt.v)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+type recursiveConfig struct {
+	Name   string `env:"NAME"`
+	Parent *recursiveConfig
+	Child  *recursiveChild
+}
+
+type recursiveChild struct {
+	Size  int `env:"SIZE"`
+	Owner *recursiveConfig
+}
+
+func TestRulesFromStruct_Recursive(t *testing.T) {
+	rules, err := RulesFromStruct(&recursiveConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range rules {
+		keys = append(keys, r.Key)
+	}
+	if got := strings.Join(keys, ","); got != "NAME,CHILD_SIZE" {
+		t.Errorf("expected recursive fields to be skipped, got %s", got)
+	}
+
+	cfg := recursiveConfig{}
+	if _, err := Bind(&cfg, WithEnvReader(MapEnvReader{"NAME": "a", "CHILD_SIZE": "2"}), WithOutput(io.Discard)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "a" || cfg.Child == nil || cfg.Child.Size != 2 || cfg.Parent != nil || cfg.Child.Owner != nil {
+		t.Errorf("unexpected bound config %+v", cfg)
+	}
+}
+
+func TestRulesFromStruct_IntBounds(t *testing.T) {
+	type sized struct {
+		I8      int8              `env:"I8"`
+		U16     *uint16           `env:"U16"`
+		U       uint              `env:"U"`
+		I       int               `env:"I"`
+		Timeout time.Duration     `env:"TIMEOUT"`
+		Ports   []int16           `env:"PORTS"`
+		Weights map[string]uint8  `env:"WEIGHTS"`
+		Raw     []byte            `env:"RAW"`
+		Limits  map[string]uint32 `env:"LIMITS" envdoc:"max=10"`
+	}
+	rules, err := RulesFromStruct(sized{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]string{
+		"I8": {"-128", "127"}, "U16": {"0", "65535"}, "U": {"0", ""}, "I": {"", ""}, "TIMEOUT": {"", ""},
+		"PORTS": {"-32768", "32767"}, "WEIGHTS": {"0", "255"}, "RAW": {"", ""}, "LIMITS": {"0", "10"},
+	}
+	for _, r := range rules {
+		if got := [2]string{r.Min, r.Max}; got != want[r.Key] {
+			t.Errorf("%s: got min/max %q, want %q", r.Key, got, want[r.Key])
+		}
+	}
+
+	var cfg struct {
+		P uint8 `env:"P"`
+	}
+	report, err := Bind(&cfg, WithEnvReader(MapEnvReader{"P": "-1"}), WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "ENV_BELOW_MIN") || report.Results[0].Valid {
+		t.Errorf("expected P=-1 to be invalid for a uint8, got %v", err)
+	}
+}
+
+func TestUpperSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"DB": "DB", "HTTPServer": "HTTP_SERVER", "RedisCache": "REDIS_CACHE", "OAuth2Client": "O_AUTH2_CLIENT", "S3": "S3",
+	} {
+		if got := upperSnake(in); got != want {
+			t.Errorf("upperSnake(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package envdoc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// This file implements a TOML 1.0 parser for rules files. Rather than
+// decoding into Go values itself, it builds a yaml.Node tree, so TOML rules
+// go through the same decoding (and the same UnmarshalYAML hooks) as YAML
+// ones. Nodes carry their TOML line numbers, so decoding errors point into
+// the TOML file. Dates and times are kept as strings.
+
+// tomlError is a TOML syntax error at a line.
+type tomlError struct {
+	line int
+	msg  string
+}
+
+func (e *tomlError) Error() string { return fmt.Sprintf("line %d: %s", e.line, e.msg) }
+
+// parseTOML parses a TOML document into a yaml mapping node.
+func parseTOML(data []byte) (node *yaml.Node, err error) {
+	if !utf8.Valid(data) {
+		return nil, &tomlError{1, "document is not valid UTF-8"}
+	}
+	p := &tomlParser{
+		src:     string(data),
+		line:    1,
+		root:    &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1},
+		defined: make(map[*yaml.Node]bool),
+		arrays:  make(map[*yaml.Node]bool),
+		static:  make(map[*yaml.Node]bool),
+	}
+	p.table = p.root
+	defer func() {
+		if r := recover(); r != nil {
+			te, ok := r.(*tomlError)
+			if !ok {
+				panic(r)
+			}
+			node, err = nil, te
+		}
+	}()
+	p.parse()
+	return p.root, nil
+}
+
+// tomlParser holds the parse state. defined marks tables opened by a
+// [header], arrays marks sequences created by [[headers]], and static marks
+// inline tables and arrays, which cannot be extended.
+type tomlParser struct {
+	src   string
+	pos   int
+	line  int
+	root  *yaml.Node
+	table *yaml.Node
+
+	defined map[*yaml.Node]bool
+	arrays  map[*yaml.Node]bool
+	static  map[*yaml.Node]bool
+}
+
+// tomlKey is one part of a dotted key.
+type tomlKey struct {
+	name string
+	line int
+}
+
+func (p *tomlParser) fail(format string, args ...any) {
+	panic(&tomlError{p.line, fmt.Sprintf(format, args...)})
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *tomlParser) expect(c byte) {
+	if p.peek() != c {
+		p.fail("expected %q, found %s", c, p.describe())
+	}
+	p.next()
+}
+
+// describe names the next character for error messages.
+func (p *tomlParser) describe() string {
+	if p.eof() {
+		return "end of file"
+	}
+	switch c := p.peek(); c {
+	case '\n', '\r':
+		return "end of line"
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return strconv.QuoteRune(r)
+	}
+}
+
+// skipSpace skips spaces and tabs.
+func (p *tomlParser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.next()
+	}
+}
+
+// skipComment skips a comment up to, not including, the newline.
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		if c := p.next(); c < 0x20 && c != '\t' && !(c == '\r' && p.peek() == '\n') {
+			p.fail("control character in comment")
+		}
+	}
+}
+
+// skipBlank skips whitespace, comments and newlines.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		switch p.peek() {
+		case '\n':
+			p.next()
+		case '\r':
+			p.next()
+			p.expect('\n')
+		default:
+			return
+		}
+	}
+}
+
+// endLine consumes the rest of a line after a key/value pair or header.
+func (p *tomlParser) endLine() {
+	p.skipSpace()
+	p.skipComment()
+	switch p.peek() {
+	case 0:
+		if !p.eof() {
+			p.fail("unexpected NUL character")
+		}
+	case '\n':
+		p.next()
+	case '\r':
+		p.next()
+		p.expect('\n')
+	default:
+		p.fail("expected end of line, found %s", p.describe())
+	}
+}
+
+func (p *tomlParser) parse() {
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return
+		}
+		if p.peek() == '[' {
+			p.header()
+		} else {
+			p.keyValue(p.table)
+		}
+		p.endLine()
+	}
+}
+
+// header parses a [table] or [[array]] header and makes it current.
+func (p *tomlParser) header() {
+	line := p.line
+	p.next()
+	array := p.peek() == '['
+	if array {
+		p.next()
+	}
+	p.skipSpace()
+	keys := p.key()
+	p.skipSpace()
+	p.expect(']')
+	if array {
+		p.expect(']')
+	}
+
+	parent := p.root
+	for _, k := range keys[:len(keys)-1] {
+		parent = p.descend(parent, k, true)
+	}
+	last := keys[len(keys)-1]
+	existing := lookupNode(parent, last.name)
+
+	if array {
+		if existing == nil {
+			existing = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line, Column: 1}
+			p.arrays[existing] = true
+			appendPair(parent, last, existing)
+		} else if !p.arrays[existing] {
+			p.fail("key %q is already defined and is not an array of tables", joinKeys(keys))
+		}
+		table := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: 1}
+		existing.Content = append(existing.Content, table)
+		p.table = table
+		return
+	}
+
+	switch {
+	case existing == nil:
+		existing = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line, Column: 1}
+		appendPair(parent, last, existing)
+	case existing.Kind != yaml.MappingNode || p.defined[existing] || p.static[existing]:
+		p.fail("table %q is already defined", joinKeys(keys))
+	}
+	p.defined[existing] = true
+	p.table = existing
+}
+
+// descend returns the table under key k of parent, creating it if needed.
+// In headers an array of tables resolves to its last table.
+func (p *tomlParser) descend(parent *yaml.Node, k tomlKey, header bool) *yaml.Node {
+	child := lookupNode(parent, k.name)
+	switch {
+	case child == nil:
+		child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: k.line, Column: 1}
+		appendPair(parent, k, child)
+		return child
+	case header && p.arrays[child]:
+		return child.Content[len(child.Content)-1]
+	case child.Kind == yaml.MappingNode && !p.static[child]:
+		if !header && p.defined[child] {
+			p.fail("cannot extend table %q with a dotted key", k.name)
+		}
+		return child
+	}
+	p.fail("key %q is already defined and is not a table", k.name)
+	return nil
+}
+
+// keyValue parses "key = value" into table.
+func (p *tomlParser) keyValue(table *yaml.Node) {
+	keys := p.key()
+	p.skipSpace()
+	p.expect('=')
+	p.skipSpace()
+	value := p.value()
+
+	for _, k := range keys[:len(keys)-1] {
+		table = p.descend(table, k, false)
+	}
+	last := keys[len(keys)-1]
+	if lookupNode(table, last.name) != nil {
+		p.fail("duplicate key %q", joinKeys(keys))
+	}
+	appendPair(table, last, value)
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() []tomlKey {
+	var keys []tomlKey
+	for {
+		line := p.line
+		var name string
+		switch c := p.peek(); {
+		case c == '"':
+			name = p.basicString()
+		case c == '\'':
+			name = p.literalString()
+		case isBareKeyChar(c):
+			start := p.pos
+			for isBareKeyChar(p.peek()) {
+				p.next()
+			}
+			name = p.src[start:p.pos]
+		default:
+			p.fail("expected a key, found %s", p.describe())
+		}
+		keys = append(keys, tomlKey{name, line})
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys
+		}
+		p.next()
+		p.skipSpace()
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses any TOML value.
+func (p *tomlParser) value() *yaml.Node {
+	line, col := p.line, p.column()
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Line: line, Column: col}
+	}
+	switch c := p.peek(); c {
+	case '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return scalar("!!str", p.multilineString('"'))
+		}
+		return scalar("!!str", p.basicString())
+	case '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return scalar("!!str", p.multilineString('\''))
+		}
+		return scalar("!!str", p.literalString())
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+
+	tok := p.token()
+	switch {
+	case tok == "true" || tok == "false":
+		return scalar("!!bool", tok)
+	case tomlIntRe.MatchString(tok) || tomlPrefixedIntRe.MatchString(tok):
+		n, err := strconv.ParseInt(strings.ReplaceAll(tok, "_", ""), 0, 64)
+		if err != nil {
+			p.fail("integer %s out of range", tok)
+		}
+		return scalar("!!int", strconv.FormatInt(n, 10))
+	case tomlFloatRe.MatchString(tok):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(tok, "_", ""), 64)
+		if err != nil {
+			p.fail("invalid float %s", tok)
+		}
+		return scalar("!!float", strconv.FormatFloat(f, 'g', -1, 64))
+	case strings.TrimLeft(tok, "+-") == "inf":
+		if tok[0] == '-' {
+			return scalar("!!float", "-.inf")
+		}
+		return scalar("!!float", ".inf")
+	case strings.TrimLeft(tok, "+-") == "nan":
+		return scalar("!!float", ".nan")
+	case tomlDateTimeRe.MatchString(tok):
+		return scalar("!!str", strings.Replace(tok, " ", "T", 1))
+	case tok == "":
+		p.fail("expected a value, found %s", p.describe())
+	}
+	p.fail("invalid value %q", tok)
+	return nil
+}
+
+var (
+	tomlIntRe         = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlPrefixedIntRe = regexp.MustCompile(`^0(x[0-9A-Fa-f](_?[0-9A-Fa-f])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+	tomlFloatRe       = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*([eE][+-]?[0-9](_?[0-9])*)?|[eE][+-]?[0-9](_?[0-9])*)$`)
+	tomlDateTimeRe    = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)$`)
+	tomlDateRe        = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// token scans a bare value: a number, boolean, or date and time. A date
+// may be followed by a space and a time.
+func (p *tomlParser) token() string {
+	start := p.pos
+	scan := func() {
+		for c := p.peek(); isBareKeyChar(c) || c == '+' || c == '.' || c == ':'; c = p.peek() {
+			p.next()
+		}
+	}
+	scan()
+	if tomlDateRe.MatchString(p.src[start:p.pos]) && p.peek() == ' ' &&
+		p.pos+1 < len(p.src) && p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9' {
+		p.next()
+		scan()
+	}
+	return p.src[start:p.pos]
+}
+
+// column returns the 1-based column of the current position.
+func (p *tomlParser) column() int {
+	return p.pos - strings.LastIndexByte(p.src[:p.pos], '\n')
+}
+
+// array parses [v1, v2, ...], which may span lines.
+func (p *tomlParser) array() *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: p.line, Column: p.column()}
+	p.static[seq] = true
+	p.next()
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.next()
+			return seq
+		}
+		seq.Content = append(seq.Content, p.value())
+		p.skipBlank()
+		if p.peek() == ',' {
+			p.next()
+			continue
+		}
+		p.expect(']')
+		return seq
+	}
+}
+
+// inlineTable parses {k = v, ...} on a single line.
+func (p *tomlParser) inlineTable() *yaml.Node {
+	table := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: p.line, Column: p.column()}
+	p.next()
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.next()
+		p.static[table] = true
+		return table
+	}
+	for {
+		p.skipSpace()
+		p.keyValue(table)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.next()
+			continue
+		}
+		p.expect('}')
+		p.markStatic(table)
+		return table
+	}
+}
+
+// markStatic marks an inline table and the tables its dotted keys created.
+func (p *tomlParser) markStatic(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode || p.static[n] {
+		return
+	}
+	p.static[n] = true
+	for i := 1; i < len(n.Content); i += 2 {
+		p.markStatic(n.Content[i])
+	}
+}
+
+// basicString parses a single-line "..." string.
+func (p *tomlParser) basicString() string {
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' || p.peek() == '\r' {
+			p.fail("unterminated string")
+		}
+		c := p.next()
+		switch {
+		case c == '"':
+			return b.String()
+		case c == '\\':
+			p.escape(&b)
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			p.fail("control character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// literalString parses a single-line '...' string.
+func (p *tomlParser) literalString() string {
+	p.next()
+	start := p.pos
+	for {
+		if p.eof() || p.peek() == '\n' || p.peek() == '\r' {
+			p.fail("unterminated string")
+		}
+		if c := p.next(); c == '\'' {
+			return p.src[start : p.pos-1]
+		} else if c < 0x20 && c != '\t' || c == 0x7f {
+			p.fail("control character in string")
+		}
+	}
+}
+
+// multilineString parses a multi-line basic or literal string. A newline right
+// after the opening delimiter is dropped and, in basic strings, a
+// backslash at the end of a line trims the following whitespace.
+func (p *tomlParser) multilineString(quote byte) string {
+	p.pos += 3
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos++
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			p.fail("unterminated string")
+		}
+		c := p.peek()
+		if c == quote && strings.HasPrefix(p.src[p.pos:], strings.Repeat(string(quote), 3)) {
+			n := 3
+			for n < 5 && p.pos+n < len(p.src) && p.src[p.pos+n] == quote {
+				n++
+			}
+			b.WriteString(strings.Repeat(string(quote), n-3))
+			p.pos += n
+			return b.String()
+		}
+		p.next()
+		switch {
+		case c == '\\' && quote == '"':
+			if rest := strings.TrimLeft(p.src[p.pos:], " \t"); strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				for c := p.peek(); c == ' ' || c == '\t' || c == '\n' || c == '\r'; c = p.peek() {
+					p.next()
+				}
+				continue
+			}
+			p.escape(&b)
+		case c == '\r' && p.peek() == '\n', c == '\n', c == '\t':
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			p.fail("control character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape decodes the escape sequence after a backslash.
+func (p *tomlParser) escape(b *strings.Builder) {
+	if p.eof() {
+		p.fail("unterminated string")
+	}
+	switch c := p.next(); c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			p.fail("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil || code > math.MaxInt32 || !utf8.ValidRune(rune(code)) {
+			p.fail("invalid unicode escape")
+		}
+		p.pos += n
+		b.WriteRune(rune(code))
+	default:
+		p.fail("invalid escape sequence \\%c", c)
+	}
+}
+
+// lookupNode returns the value of key in a mapping node, or nil.
+func lookupNode(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func appendPair(m *yaml.Node, k tomlKey, v *yaml.Node) {
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.name, Line: k.line, Column: 1}
+	m.Content = append(m.Content, key, v)
+}
+
+func joinKeys(keys []tomlKey) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.name
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,136 @@
+package envdoc
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeTOMLValue(t *testing.T, src string) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := decodeTOML([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"strings", `
+basic = "tab\tquote\" \u00e9 \U0001F600"
+literal = 'C:\path\n'
+multi = """
+one \
+    two
+three"""
+multilit = '''
+raw \n ''line'''''
+`, map[string]any{
+			"basic":    "tab\tquote\" é 😀",
+			"literal":  `C:\path\n`,
+			"multi":    "one two\nthree",
+			"multilit": "raw \\n ''line''",
+		}},
+		{"numbers", `
+dec = +1_000
+neg = -17
+hex = 0xff
+oct = 0o17
+bin = 0b101
+flt = 6.5e-1
+exp = 1e3
+inf = -inf
+`, map[string]any{
+			"dec": 1000, "neg": -17, "hex": 255, "oct": 15, "bin": 5,
+			"flt": 0.65, "exp": 1000.0, "inf": math.Inf(-1),
+		}},
+		{"dates and bools", `
+on = true
+off = false
+date = 2026-06-01
+odt = 2026-06-01 12:30:00Z
+time = 07:32:00
+`, map[string]any{
+			"on": true, "off": false, "date": "2026-06-01",
+			"odt": "2026-06-01T12:30:00Z", "time": "07:32:00",
+		}},
+		{"tables", `
+top = 1 # comment
+a.b.c = "dotted"
+"quoted key" = 'q'
+
+[server]
+host = "db"
+
+[server.tls]
+enabled = true
+
+[[items]]
+name = "x"
+
+[[items]]
+name = "y"
+tags = [
+  "a", # first
+  "b",
+]
+
+[items.meta]
+k = { v = 1, w.x = 2 }
+`, map[string]any{
+			"top":        1,
+			"a":          map[string]any{"b": map[string]any{"c": "dotted"}},
+			"quoted key": "q",
+			"server":     map[string]any{"host": "db", "tls": map[string]any{"enabled": true}},
+			"items": []any{
+				map[string]any{"name": "x"},
+				map[string]any{"name": "y", "tags": []any{"a", "b"}, "meta": map[string]any{
+					"k": map[string]any{"v": 1, "w": map[string]any{"x": 2}},
+				}},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeTOMLValue(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a = 1\na = 2\n", `line 2: duplicate key "a"`},
+		{"[t]\n[t]\n", `line 2: table "t" is already defined`},
+		{"a = 1\n[a]\n", `line 2: table "a" is already defined`},
+		{"a = [1]\n[[a]]\n", `line 2: key "a" is already defined and is not an array of tables`},
+		{"a = {b = 1}\n[a.c]\n", `line 2: key "a" is already defined and is not a table`},
+		{"[t.sub]\nx = 1\n[t]\nsub.y = 2\n", `line 4: cannot extend table "sub" with a dotted key`},
+		{"a = \"open\n", "line 1: unterminated string"},
+		{"a = \"\\q\"\n", `line 1: invalid escape sequence \q`},
+		{"a = 1 b = 2\n", "line 1: expected end of line"},
+		{"a = \n", "line 1: expected a value, found end of line"},
+		{"a = 012\n", `line 1: invalid value "012"`},
+		{"a = 99999999999999999999\n", "line 1: integer 99999999999999999999 out of range"},
+		{"a = [1, 2\n\nb = 3\n", `line 3: expected ']'`},
+		{"= 1\n", `line 1: expected a key, found '='`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			_, err := parseTOML([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
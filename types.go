@@ -0,0 +1,69 @@
+package envdoc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TypeValidator checks a raw value of a custom type. A non-nil error is
+// reported as the problem message, so it must not include the value.
+type TypeValidator func(value string) error
+
+var (
+	typesMu     sync.RWMutex
+	customTypes = make(map[VarType]TypeValidator)
+)
+
+// RegisterType registers fn as the validator for the custom type name, which
+// rules may then use as type or item_type. Rules are checked against
+// registered types when loaded, so register before loading. Registering a
+// name again replaces its validator. It panics if name is empty or built-in,
+// or fn is nil.
+func RegisterType(name VarType, fn TypeValidator) {
+	mustCustomType(name, fn)
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	customTypes[name] = fn
+}
+
+// mustCustomType panics unless name can be used for a custom type.
+func mustCustomType(name VarType, fn TypeValidator) {
+	if name == "" {
+		panic("envdoc: custom type name is empty")
+	}
+	if validTypes[name] {
+		panic(fmt.Sprintf("envdoc: cannot redefine built-in type %q", name))
+	}
+	if fn == nil {
+		panic(fmt.Sprintf("envdoc: nil validator for type %q", name))
+	}
+}
+
+// registeredType returns the registered validator for name.
+func registeredType(name VarType) (TypeValidator, bool) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	fn, ok := customTypes[name]
+	return fn, ok
+}
+
+// knownType reports whether typ is built-in or registered.
+func knownType(typ VarType) bool {
+	if validTypes[typ] {
+		return true
+	}
+	_, ok := registeredType(typ)
+	return ok
+}
+
+// typeSet holds per-Inspector validators, which take precedence over
+// registered ones. A nil typeSet uses the registry alone.
+type typeSet map[VarType]TypeValidator
+
+// lookup returns the custom validator for typ, if any.
+func (ts typeSet) lookup(typ VarType) (TypeValidator, bool) {
+	if fn, ok := ts[typ]; ok {
+		return fn, true
+	}
+	return registeredType(typ)
+}
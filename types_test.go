@@ -0,0 +1,110 @@
+package envdoc
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^t-[0-9]{6}$`)
+
+func validTenantID(value string) error {
+	if !tenantIDPattern.MatchString(value) {
+		return errors.New("not a valid tenant_id")
+	}
+	return nil
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType("tenant_id", validTenantID)
+
+	rs, err := LoadRuleSet([]byte(`
+rules:
+  - key: TENANT
+    type: tenant_id
+  - key: TENANTS
+    type: list
+    item_type: tenant_id
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := ValidateVar("t-123456", rs.Rules[0]); len(p) != 0 {
+		t.Errorf("expected valid tenant, got %v", p)
+	}
+	if p := ValidateVar("acme", rs.Rules[0]); len(p) != 1 || p[0] != "not a valid tenant_id" {
+		t.Errorf("expected tenant_id problem, got %v", p)
+	}
+
+	env := MapEnvReader{"TENANT": "acme", "TENANTS": "t-000001,bad"}
+	report := inspect(env, fixedClock{t: time.Now()}, *rs, Config{}, nil)
+	want := Problem{Code: CodeTypeInvalid, Field: "type", Severity: SeverityError, Expected: VarType("tenant_id"), Message: "not a valid tenant_id"}
+	if r := report.Results[0]; r.Valid || len(r.Problems) != 1 || r.Problems[0] != want {
+		t.Errorf("expected %+v, got %+v", want, r.Problems)
+	}
+	if r := report.Results[1]; r.Valid || r.Problems[0].Field != "item_type" || r.Problems[0].Message != "item[1]: not a valid tenant_id" {
+		t.Errorf("expected item problem, got %+v", r.Problems)
+	}
+
+	var buf bytes.Buffer
+	LogReport(&buf, report)
+	if !strings.Contains(buf.String(), `problem="error ENV_TYPE_INVALID: not a valid tenant_id"`) {
+		t.Errorf("expected custom type problem in log, got %s", buf.String())
+	}
+}
+
+func TestWithType(t *testing.T) {
+	RegisterType("region_code", func(string) error { return errors.New("not a valid region_code") })
+
+	report := New(
+		WithEnvReader(MapEnvReader{"REGION": "eu1", "ZONE": "eu1-a"}),
+		WithRules([]Rule{{Key: "REGION", Type: "region_code"}, {Key: "ZONE", Type: "zone"}}),
+		WithConfig(Config{Mode: ModeAllowlist}),
+		WithType("region_code", func(v string) error { return nil }),
+		WithType("zone", func(v string) error { return errors.New("not a valid zone") }),
+	).Inspect()
+
+	if !report.Results[0].Valid {
+		t.Errorf("expected per-instance validator to override registry, got %v", report.Results[0].Problems)
+	}
+	if r := report.Results[1]; r.Valid || r.Problems[0].Message != "not a valid zone" {
+		t.Errorf("expected per-instance type problem, got %v", r.Problems)
+	}
+	// Per-instance types do not leak into ValidateVar or other inspectors.
+	if p := ValidateVar("eu1", Rule{Type: "region_code"}); len(p) != 1 {
+		t.Errorf("expected registered validator outside the inspector, got %v", p)
+	}
+}
+
+func TestRegisterType_Panics(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  VarType
+		fn   TypeValidator
+	}{
+		{"empty", "", validTenantID},
+		{"built-in", TypeInt, validTenantID},
+		{"nil", "nothing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			RegisterType(tt.typ, tt.fn)
+		})
+	}
+}
+
+func TestValidateRules_UnregisteredType(t *testing.T) {
+	_, err := LoadRuleSet([]byte("rules:\n  - key: A\n    type: not_registered\n"))
+	if err == nil || !strings.Contains(err.Error(), `unknown type "not_registered"`) {
+		t.Errorf("expected unknown type error, got %v", err)
+	}
+}
@@ -1,40 +1,72 @@
 package envdoc
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // ValidateVar validates a value against a Rule and returns a list of problems.
-// An empty return means the value is valid.
+// An empty return means the value is valid. Problems are returned regardless
+// of their severity.
 func ValidateVar(value string, rule Rule) []string {
-	var problems []string
+	return problemMessages(checkVar(value, rule, nil))
+}
+
+// checkVar validates value against rule and returns problems tagged with the
+// rule field that failed. Severities are assigned by the caller. types
+// supplies custom type validators beyond the registry.
+func checkVar(value string, rule Rule, types typeSet) []Problem {
+	switch rule.Type {
+	case TypeList:
+		return checkList(value, rule, types)
+	case TypeMap:
+		return checkMap(value, rule, types)
+	}
+
+	var problems []Problem
 
 	// Type check
+	typeOK := true
 	if rule.Type != "" {
-		if err := checkType(value, rule.Type); err != nil {
-			problems = append(problems, err.Error())
+		if err := checkType(value, rule.Type, types); err != nil {
+			problems = append(problems, newProblem(CodeTypeInvalid, "type", rule.Type, "%s", err))
+			typeOK = false
 		}
 	}
 
-	// Length checks
-	if rule.MinLen != nil && len(value) < *rule.MinLen {
-		problems = append(problems, fmt.Sprintf("length %d < min_len %d", len(value), *rule.MinLen))
+	// IP version restriction
+	if typeOK && rule.IPVersion != 0 {
+		if err := checkIPVersion(value, rule.Type, rule.IPVersion); err != nil {
+			problems = append(problems, newProblem(CodeIPVersion, "ip_version", rule.IPVersion, "%s", err))
+		}
 	}
-	if rule.MaxLen != nil && len(value) > *rule.MaxLen {
-		problems = append(problems, fmt.Sprintf("length %d > max_len %d", len(value), *rule.MaxLen))
+
+	// JSON schema (only meaningful once the value parses)
+	if typeOK && rule.JSONSchema != nil {
+		problems = append(problems, checkJSONSchema(value, rule.JSONSchema)...)
 	}
 
+	// Range checks (only meaningful once the value parses)
+	if typeOK {
+		problems = append(problems, checkRange(value, rule)...)
+	}
+
+	// Length checks
+	problems = append(problems, checkLength(value, rule)...)
+
 	// Regex check
 	if rule.Regex != "" {
 		re, err := regexp.Compile(rule.Regex)
 		if err == nil && !re.MatchString(value) {
-			problems = append(problems, fmt.Sprintf("does not match regex %q", rule.Regex))
+			problems = append(problems, newProblem(CodeRegexMismatch, "regex", rule.Regex, "does not match regex %q", rule.Regex))
 		}
 	}
 
@@ -48,41 +80,279 @@ func ValidateVar(value string, rule Rule) []string {
 			}
 		}
 		if !found {
-			problems = append(problems, fmt.Sprintf("value not in allowed set [%s]", strings.Join(rule.Allowed, ", ")))
+			problems = append(problems, newProblem(CodeNotAllowed, "allowed", rule.Allowed, "value not in allowed set [%s]", strings.Join(rule.Allowed, ", ")))
 		}
 	}
 
 	return problems
 }
 
+// checkLength validates the length of the raw value against min_len/max_len.
+func checkLength(value string, rule Rule) []Problem {
+	var problems []Problem
+	if rule.MinLen != nil && len(value) < *rule.MinLen {
+		problems = append(problems, newProblem(CodeTooShort, "min_len", *rule.MinLen, "length %d < min_len %d", len(value), *rule.MinLen))
+	}
+	if rule.MaxLen != nil && len(value) > *rule.MaxLen {
+		problems = append(problems, newProblem(CodeTooLong, "max_len", *rule.MaxLen, "length %d > max_len %d", len(value), *rule.MaxLen))
+	}
+	return problems
+}
+
+// checkList validates a TypeList value. Length limits apply to the raw
+// value; type, range, regex and allowed checks apply to each item. Item
+// problems are reported by index so values are never echoed.
+func checkList(value string, rule Rule, types typeSet) []Problem {
+	problems := checkLength(value, rule)
+
+	items := splitList(value, rule.listSeparator())
+	if rule.MinItems != nil && len(items) < *rule.MinItems {
+		problems = append(problems, newProblem(CodeTooFewItems, "min_items", *rule.MinItems, "%d items < min_items %d", len(items), *rule.MinItems))
+	}
+	if rule.MaxItems != nil && len(items) > *rule.MaxItems {
+		problems = append(problems, newProblem(CodeTooManyItems, "max_items", *rule.MaxItems, "%d items > max_items %d", len(items), *rule.MaxItems))
+	}
+
+	itemRule := rule.elementRule()
+	seen := make(map[string]int)
+	for idx, item := range items {
+		if item == "" {
+			problems = append(problems, newProblem(CodeEmptyItem, "item_type", nil, "item[%d]: empty", idx))
+			continue
+		}
+		for _, p := range checkVar(item, itemRule, types) {
+			problems = append(problems, elementProblem(p, fmt.Sprintf("item[%d]: ", idx)))
+		}
+		if rule.Unique {
+			if first, dup := seen[item]; dup {
+				problems = append(problems, newProblem(CodeDuplicateItem, "unique", true, "item[%d]: duplicate of item[%d]", idx, first))
+			} else {
+				seen[item] = idx
+			}
+		}
+	}
+	return problems
+}
+
+// elementProblem prefixes a list item or map value problem with its
+// position. The element's type check is reported against item_type.
+func elementProblem(p Problem, prefix string) Problem {
+	if p.Field == "type" {
+		p.Field = "item_type"
+	}
+	p.Message = prefix + p.Message
+	return p
+}
+
+// mapPair is a single key/value pair of a TypeMap value. OK is false when
+// the pair has no key/value separator.
+type mapPair struct {
+	Key   string
+	Value string
+	OK    bool
+}
+
+// checkMap validates a TypeMap value. Length limits apply to the raw value;
+// item checks apply to each value. Pair problems are reported by index.
+func checkMap(value string, rule Rule, types typeSet) []Problem {
+	problems := checkLength(value, rule)
+
+	pairs := splitMap(value, rule.listSeparator(), rule.kvSeparator())
+	if rule.MinItems != nil && len(pairs) < *rule.MinItems {
+		problems = append(problems, newProblem(CodeTooFewItems, "min_items", *rule.MinItems, "%d pairs < min_items %d", len(pairs), *rule.MinItems))
+	}
+	if rule.MaxItems != nil && len(pairs) > *rule.MaxItems {
+		problems = append(problems, newProblem(CodeTooManyItems, "max_items", *rule.MaxItems, "%d pairs > max_items %d", len(pairs), *rule.MaxItems))
+	}
+
+	valueRule := rule.elementRule()
+	seen := make(map[string]int)
+	for idx, p := range pairs {
+		if !p.OK {
+			problems = append(problems, newProblem(CodeMapMalformed, "type", rule.Type, "pair[%d]: missing %q", idx, rule.kvSeparator()))
+			continue
+		}
+		if p.Key == "" {
+			problems = append(problems, newProblem(CodeMapMalformed, "type", rule.Type, "pair[%d]: empty key", idx))
+			continue
+		}
+		if first, dup := seen[p.Key]; dup {
+			problems = append(problems, newProblem(CodeDuplicateItem, "type", rule.Type, "pair[%d]: duplicate key of pair[%d]", idx, first))
+		} else {
+			seen[p.Key] = idx
+		}
+		if len(rule.AllowedKeys) > 0 && !slices.Contains(rule.AllowedKeys, p.Key) {
+			problems = append(problems, newProblem(CodeKeyNotAllowed, "allowed_keys", rule.AllowedKeys, "pair[%d]: key not in allowed_keys", idx))
+		}
+		for _, vp := range checkVar(p.Value, valueRule, types) {
+			problems = append(problems, elementProblem(vp, fmt.Sprintf("pair[%d] value: ", idx)))
+		}
+	}
+
+	for _, k := range rule.RequiredKeys {
+		if _, ok := seen[k]; !ok {
+			problems = append(problems, newProblem(CodeMissingKey, "required_keys", k, "missing required key %q", k))
+		}
+	}
+	return problems
+}
+
+// splitMap splits a map value into pairs, trimming whitespace around keys
+// and values. An empty value has no pairs.
+func splitMap(value, sep, kvSep string) []mapPair {
+	items := splitList(value, sep)
+	pairs := make([]mapPair, 0, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, kvSep)
+		pairs = append(pairs, mapPair{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v), OK: ok})
+	}
+	return pairs
+}
+
+// mapKeys returns the distinct keys of a map value in order of appearance.
+func mapKeys(pairs []mapPair) []string {
+	var keys []string
+	for _, p := range pairs {
+		if p.OK && p.Key != "" && !slices.Contains(keys, p.Key) {
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// splitList splits a list value on sep and trims whitespace around each item.
+// An empty value has no items.
+func splitList(value, sep string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	items := strings.Split(value, sep)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
 // checkType validates a string value against the expected VarType.
-func checkType(value string, typ VarType) error {
+func checkType(value string, typ VarType, types typeSet) error {
+	if fn, ok := types.lookup(typ); ok {
+		return fn(value)
+	}
+	_, err := parseValue(value, typ)
+	return err
+}
+
+// parseValue parses a string value according to typ and returns its Go
+// representation: int64, float64, bool, time.Duration, *url.URL, netip.Addr,
+// netip.Prefix, hostPort or string. Ports parse to int64. JSON values are
+// validated but returned unparsed, and custom types are returned as strings.
+func parseValue(value string, typ VarType) (any, error) {
 	switch typ {
-	case TypeString:
-		return nil
 	case TypeInt:
-		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
-			return fmt.Errorf("not a valid int")
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid int")
 		}
+		return n, nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("not a valid float")
+		}
+		return f, nil
 	case TypeBool:
-		if _, err := strconv.ParseBool(value); err != nil {
-			return fmt.Errorf("not a valid bool")
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid bool")
 		}
+		return b, nil
 	case TypeDuration:
-		if _, err := time.ParseDuration(value); err != nil {
-			return fmt.Errorf("not a valid duration")
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid duration")
 		}
+		return d, nil
 	case TypeURL:
 		u, err := url.Parse(value)
 		if err != nil || u.Scheme == "" || u.Host == "" {
-			return fmt.Errorf("not a valid url")
+			return nil, fmt.Errorf("not a valid url")
 		}
+		return u, nil
 	case TypeJSON:
 		if !json.Valid([]byte(value)) {
-			return fmt.Errorf("not valid json")
+			return nil, fmt.Errorf("not valid json")
+		}
+	case TypeIP:
+		return parseIP(value)
+	case TypeCIDR:
+		return parseCIDR(value)
+	case TypeHostname:
+		return parseHostname(value)
+	case TypePort:
+		return parsePort(value)
+	case TypeHostPort:
+		return parseHostPort(value)
+	}
+	return value, nil
+}
+
+// orderedTypes are the VarTypes whose parsed values can be compared with min/max.
+var orderedTypes = map[VarType]bool{
+	TypeInt: true, TypeFloat: true, TypeDuration: true, TypePort: true,
+}
+
+// compareParsed compares two values returned by parseValue for the same
+// ordered type. It returns -1, 0 or +1.
+func compareParsed(a, b any) int {
+	switch x := a.(type) {
+	case int64:
+		return cmp.Compare(x, b.(int64))
+	case float64:
+		return cmp.Compare(x, b.(float64))
+	case time.Duration:
+		return cmp.Compare(x, b.(time.Duration))
+	}
+	return 0
+}
+
+// checkRange validates value against the rule's min/max bounds. Problems
+// name the bound but never the value itself.
+func checkRange(value string, rule Rule) []Problem {
+	if rule.Min == "" && rule.Max == "" {
+		return nil
+	}
+	if !orderedTypes[rule.Type] {
+		return nil
+	}
+	v, err := parseValue(value, rule.Type)
+	if err != nil {
+		return nil
+	}
+
+	var problems []Problem
+	if rule.Min != "" {
+		if min, err := parseValue(rule.Min, rule.Type); err == nil {
+			c := compareParsed(v, min)
+			switch {
+			case rule.ExclusiveMin && c <= 0:
+				problems = append(problems, newProblem(CodeBelowMin, "min", rule.Min, "value not above exclusive minimum %s", rule.Min))
+			case c < 0:
+				problems = append(problems, newProblem(CodeBelowMin, "min", rule.Min, "value below minimum %s", rule.Min))
+			}
 		}
 	}
-	return nil
+	if rule.Max != "" {
+		if max, err := parseValue(rule.Max, rule.Type); err == nil {
+			c := compareParsed(v, max)
+			switch {
+			case rule.ExclusiveMax && c >= 0:
+				problems = append(problems, newProblem(CodeAboveMax, "max", rule.Max, "value not below exclusive maximum %s", rule.Max))
+			case c > 0:
+				problems = append(problems, newProblem(CodeAboveMax, "max", rule.Max, "value above maximum %s", rule.Max))
+			}
+		}
+	}
+	return problems
 }
 
 // detectWhitespace checks if a value has leading or trailing whitespace.
@@ -1,6 +1,9 @@
 package envdoc
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestValidateVar_TypeString(t *testing.T) {
 	problems := ValidateVar("hello", Rule{Type: TypeString})
@@ -71,6 +74,165 @@ func TestValidateVar_TypeJSON(t *testing.T) {
 	}
 }
 
+func TestValidateVar_TypeFloat(t *testing.T) {
+	problems := ValidateVar("0.25", Rule{Type: TypeFloat})
+	if len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	for _, v := range []string{"abc", "NaN", "Inf"} {
+		problems = ValidateVar(v, Rule{Type: TypeFloat})
+		if len(problems) != 1 {
+			t.Errorf("expected 1 problem for %q, got %v", v, problems)
+		}
+	}
+}
+
+func TestValidateVar_IntRange(t *testing.T) {
+	rule := Rule{Type: TypeInt, Min: "1", Max: "65535"}
+	for _, v := range []string{"1", "5432", "65535"} {
+		if problems := ValidateVar(v, rule); len(problems) != 0 {
+			t.Errorf("expected no problems for %q, got %v", v, problems)
+		}
+	}
+
+	problems := ValidateVar("0", rule)
+	if len(problems) != 1 || problems[0] != "value below minimum 1" {
+		t.Errorf("expected below minimum problem, got %v", problems)
+	}
+	problems = ValidateVar("70000", rule)
+	if len(problems) != 1 || problems[0] != "value above maximum 65535" {
+		t.Errorf("expected above maximum problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_ExclusiveRange(t *testing.T) {
+	rule := Rule{Type: TypeFloat, Min: "0", Max: "1", ExclusiveMin: true}
+	if problems := ValidateVar("0", rule); len(problems) != 1 {
+		t.Errorf("expected 1 problem for exclusive min, got %v", problems)
+	}
+	if problems := ValidateVar("1", rule); len(problems) != 0 {
+		t.Errorf("expected inclusive max to accept 1, got %v", problems)
+	}
+}
+
+func TestValidateVar_DurationRange(t *testing.T) {
+	rule := Rule{Type: TypeDuration, Min: "1s", Max: "1h"}
+	if problems := ValidateVar("30m", rule); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+	if problems := ValidateVar("500ms", rule); len(problems) != 1 {
+		t.Errorf("expected 1 problem, got %v", problems)
+	}
+	if problems := ValidateVar("2h", rule); len(problems) != 1 {
+		t.Errorf("expected 1 problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_RangeNeverEchoesValue(t *testing.T) {
+	problems := ValidateVar("987654", Rule{Type: TypeInt, Max: "10"})
+	for _, p := range problems {
+		if strings.Contains(p, "987654") {
+			t.Errorf("problem leaks value: %q", p)
+		}
+	}
+}
+
+func TestValidateVar_RangeSkippedOnTypeError(t *testing.T) {
+	problems := ValidateVar("abc", Rule{Type: TypeInt, Min: "1"})
+	if len(problems) != 1 {
+		t.Errorf("expected only the type problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_List(t *testing.T) {
+	rule := Rule{Type: TypeList, ItemType: TypeHostPort, MinItems: intPtr(1), MaxItems: intPtr(3)}
+	if problems := ValidateVar("k1:9092, k2:9092,k3:9092", rule); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	problems := ValidateVar("k1:9092,k2,k3:9092", rule)
+	if len(problems) != 1 || problems[0] != "item[1]: hostport: missing port" {
+		t.Errorf("expected item[1] problem, got %v", problems)
+	}
+
+	problems = ValidateVar("", rule)
+	if len(problems) != 1 || problems[0] != "0 items < min_items 1" {
+		t.Errorf("expected min_items problem, got %v", problems)
+	}
+
+	problems = ValidateVar("a:1,b:1,c:1,d:1", rule)
+	if len(problems) != 1 || problems[0] != "4 items > max_items 3" {
+		t.Errorf("expected max_items problem, got %v", problems)
+	}
+}
+
+func TestValidateVar_ListItems(t *testing.T) {
+	rule := Rule{
+		Type:      TypeList,
+		Separator: ";",
+		ItemType:  TypeInt,
+		Max:       "10",
+		Allowed:   []string{"1", "2", "3", "20"},
+		Unique:    true,
+	}
+	problems := ValidateVar("1;2;;2;20", rule)
+	want := []string{
+		"item[2]: empty",
+		"item[3]: duplicate of item[1]",
+		"item[4]: value above maximum 10",
+	}
+	if strings.Join(problems, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, problems)
+	}
+}
+
+func TestValidateVar_ListRegexPerItem(t *testing.T) {
+	rule := Rule{Type: TypeList, Regex: `^https://`, MaxLen: intPtr(40)}
+	problems := ValidateVar("https://a.example.com,http://b.example.com", rule)
+	if len(problems) != 2 {
+		t.Fatalf("expected max_len and item regex problems, got %v", problems)
+	}
+	if !strings.HasPrefix(problems[0], "length ") || !strings.HasPrefix(problems[1], "item[1]: does not match regex") {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestValidateVar_Map(t *testing.T) {
+	rule := Rule{
+		Type:         TypeMap,
+		RequiredKeys: []string{"service.name"},
+		AllowedKeys:  []string{"service.name", "deployment.environment", "replicas"},
+	}
+	if problems := ValidateVar("service.name=api, deployment.environment=prod", rule); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	problems := ValidateVar("deployment.environment=prod,team=core,broken", rule)
+	want := []string{
+		"pair[1]: key not in allowed_keys",
+		`pair[2]: missing "="`,
+		`missing required key "service.name"`,
+	}
+	if strings.Join(problems, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, problems)
+	}
+}
+
+func TestValidateVar_MapValues(t *testing.T) {
+	rule := Rule{Type: TypeMap, Separator: ";", KVSeparator: ":", ItemType: TypeInt, Min: "0"}
+	problems := ValidateVar("a:1;b:x;a:2;c:-1;:3", rule)
+	want := []string{
+		"pair[1] value: not a valid int",
+		"pair[2]: duplicate key of pair[0]",
+		"pair[3] value: value below minimum 0",
+		"pair[4]: empty key",
+	}
+	if strings.Join(problems, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, problems)
+	}
+}
+
 func TestValidateVar_MinLen(t *testing.T) {
 	min := 5
 	problems := ValidateVar("hi", Rule{MinLen: &min})